@@ -0,0 +1,254 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"yrk06/chess-backend/engine"
+)
+
+// Most lines an analysis can ask for
+const MAX_ANALYSIS_LINES = 32
+
+// Milliseconds an analysis runs without a movetime, and the longest movetime it can ask for
+const ANALYSIS_MOVETIME = 10000
+const MAX_ANALYSIS_MOVETIME = 60000
+
+// One of the best moves of an analysed position
+type analysisLine struct {
+	// UCI notation
+	Move string `json:"move"`
+	// Centipawns, white positive
+	Score float64 `json:"score"`
+	// Moves to mate, positive when white mates and 0 if there is no mate
+	Mate int `json:"mate"`
+	// Expected line in SAN, starting with Move
+	PV []string `json:"pv"`
+}
+
+// Result of an iteration of the analysis of a position
+type analysisReport struct {
+	Fen    string         `json:"fen"`
+	Depth  int            `json:"depth"`
+	States int            `json:"states"`
+	Time   int64          `json:"time"` // Milliseconds
+	Lines  []analysisLine `json:"lines"`
+	// Last report of the position
+	Final bool `json:"final"`
+}
+
+// Analysis settings read from the query string
+type analysisRequest struct {
+	fen     string
+	multipv int
+	limits  engine.SearchLimits
+}
+
+/*
+	Reads the query string VALUES: fen (start position by default), multipv
+	(lines, 1 by default), depth and movetime (milliseconds). Without a depth
+	the analysis searches to -depth, or as deep as the movetime allows if
+	one is given. It never runs longer than MAX_ANALYSIS_MOVETIME
+*/
+func parse_analysis(values url.Values) (analysisRequest, error) {
+	req := analysisRequest{fen: START_FEN, multipv: 1}
+	req.limits = engine.MoveTime(ANALYSIS_MOVETIME * time.Millisecond)
+	req.limits.Depth = *depth
+	if fen := values.Get("fen"); fen != "" {
+		req.fen = fen
+	}
+	if _, err := analysis_board(req.fen); err != nil {
+		return req, err
+	}
+
+	if lines := values.Get("multipv"); lines != "" {
+		multipv, err := strconv.Atoi(lines)
+		if err != nil || multipv < 1 || multipv > MAX_ANALYSIS_LINES {
+			return req, errors.New("multipv must be between 1 and " + strconv.Itoa(MAX_ANALYSIS_LINES))
+		}
+		req.multipv = multipv
+	}
+	if movetime := values.Get("movetime"); movetime != "" {
+		ms, err := strconv.Atoi(movetime)
+		if err != nil || ms <= 0 || ms > MAX_ANALYSIS_MOVETIME {
+			return req, errors.New("movetime must be between 1 and " + strconv.Itoa(MAX_ANALYSIS_MOVETIME) + " milliseconds")
+		}
+		req.limits = engine.MoveTime(time.Duration(ms) * time.Millisecond)
+	}
+	if depth := values.Get("depth"); depth != "" {
+		d, err := strconv.Atoi(depth)
+		if err != nil || d < 1 || d > engine.MAX_SEARCH_DEPTH {
+			return req, errors.New("depth must be between 1 and " + strconv.Itoa(engine.MAX_SEARCH_DEPTH))
+		}
+		req.limits.Depth = d
+	}
+	return req, nil
+}
+
+// Board of the position FEN, checked enough for the engine to load it
+func analysis_board(fen string) (engine.Chessboard, error) {
+	board := engine.Chessboard{}
+	fields := strings.Fields(fen)
+	if len(fields) < 4 || len(strings.Split(fields[0], "/")) != 8 {
+		return board, errors.New("invalid fen")
+	}
+	if strings.Count(fields[0], "K") != 1 || strings.Count(fields[0], "k") != 1 {
+		return board, errors.New("invalid fen: each side needs a king")
+	}
+	board.FromFen(fen)
+	return board, nil
+}
+
+// Report of the search INFO of BOARD
+func analysis_report(board *engine.Chessboard, info engine.SearchInfo) analysisReport {
+	report := analysisReport{
+		Fen:    board.Fen(),
+		Depth:  info.Depth,
+		States: info.States,
+		Time:   info.Elapsed.Milliseconds(),
+		Lines:  []analysisLine{},
+	}
+	for _, line := range info.Lines {
+		report.Lines = append(report.Lines, analysisLine{
+			Move:  board.MoveUCI(line.Move),
+			Score: line.Score,
+			Mate:  line.Mate,
+			PV:    board.LineSAN(line.PV),
+		})
+	}
+	return report
+}
+
+/*
+	Analyses with BOT the position REQ asks for, calling REPORT after every
+	iteration (may be nil). Cancelling CTX ends the analysis, which still
+	returns the last finished iteration
+*/
+func run_analysis(ctx context.Context, bot *engine.Engine, req analysisRequest, report func(analysisReport)) analysisReport {
+	board, _ := analysis_board(req.fen)
+	bot.MultiPV = req.multipv
+	// Analysis shows the best moves, not the ones the bot would play
+	bot.Variety = 0
+
+	// Only this analysis is stopped, the watcher is gone before the next one starts
+	bot.ClearStop()
+	finished := make(chan struct{})
+	watcher := make(chan struct{})
+	go func() {
+		defer close(watcher)
+		select {
+		case <-ctx.Done():
+			bot.Stop()
+		case <-finished:
+		}
+	}()
+	defer func() {
+		close(finished)
+		<-watcher
+	}()
+
+	info := bot.Think(&board, req.limits, func(info engine.SearchInfo) {
+		if report != nil {
+			report(analysis_report(&board, info))
+		}
+	})
+	final := analysis_report(&board, info)
+	final.Final = true
+	return final
+}
+
+// GET /analyze?fen=...&multipv=3&depth=8 answers with the JSON report of the analysis
+func analyze(w http.ResponseWriter, r *http.Request) {
+	req, err := parse_analysis(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// The analysis ends if the client leaves, nobody waits for it
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(run_analysis(r.Context(), new_bot(), req, nil))
+}
+
+// Position received on the analysis socket, cancelled when the next one arrives
+type analysisJob struct {
+	fen    string
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+/*
+	Websocket analysis channel, the query string sets the options of /analyze.
+	Every message is the FEN of a position to analyse, answered with a JSON
+	report per iteration and a last one with "final" set. A new message ends
+	the analysis of the last position, closing the socket ends it too
+*/
+func analysis(w http.ResponseWriter, r *http.Request) {
+	req, err := parse_analysis(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	c, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Print("upgrade:", err)
+		return
+	}
+	defer c.Close()
+
+	// Read while the engine searches
+	jobs := make(chan analysisJob)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		defer close(jobs)
+		last := analysisJob{cancel: func() {}}
+		defer func() { last.cancel() }()
+		for {
+			_, message, err := c.ReadMessage()
+			// A new position or a closed socket ends the running analysis
+			last.cancel()
+			if err != nil {
+				log.Println("read:", err)
+				return
+			}
+			job := analysisJob{fen: strings.TrimSpace(string(message))}
+			job.ctx, job.cancel = context.WithCancel(context.Background())
+			last = job
+			select {
+			case jobs <- job:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	// One engine for the whole connection
+	bot := new_bot()
+	for job := range jobs {
+		// Replaced before it started
+		if job.ctx.Err() != nil {
+			continue
+		}
+		req.fen = job.fen
+		if _, err := analysis_board(req.fen); err != nil {
+			c.WriteJSON(map[string]string{"error": err.Error()})
+			continue
+		}
+
+		final := run_analysis(job.ctx, bot, req, func(report analysisReport) {
+			c.WriteJSON(report)
+		})
+		if err := c.WriteJSON(final); err != nil {
+			log.Println("write:", err)
+			return
+		}
+	}
+}
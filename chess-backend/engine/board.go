@@ -0,0 +1,808 @@
+package engine
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"yrk06/chess-backend/moveset"
+)
+
+/*
+Offset	Piece
+0		PAWN
+3584	BLACK PAWN
+7168	KNIGHT
+10752	ROOK
+14336	BISHOP
+17920	QUEEN
+21504	KING
+*/
+
+// piece type to movement memory offset map  Tabela de offset de memoria (movimentos) pra cada peça
+var pieceMap = map[byte]int{
+	'p': 0,
+	'P': 3584,
+
+	'n': 7168,
+	'N': 7168,
+
+	'r': 10752,
+	'R': 10752,
+
+	'b': 14336,
+	'B': 14336,
+
+	'q': 17920,
+	'Q': 17920,
+
+	'k': 21504,
+	'K': 25088,
+}
+
+// Location Type
+type Location struct {
+	x int
+	y int
+}
+
+// Converts a location object to FEN string index
+func (l *Location) toFen() int {
+	return (7-l.y)*9 + (l.x)
+}
+
+// Converts a location object to PGN
+func (l Location) PGN() string {
+	return fmt.Sprintf("%s%d", string(rune(l.x+97)), l.y+1)
+}
+
+// Loads a position from a PGN
+func (l *Location) FromPGN(pos string) {
+	l.x = int(pos[0]) - 97
+	l.y = int(pos[1]-'0') - 1
+}
+
+// Converts a location to Byte
+func (l *Location) toByte() uint8 {
+	return uint8((1 << 7) | ((l.x & 0b111) << 3) | (l.y & 0b111))
+}
+
+// Loads a location from a byte
+func (l *Location) fromByte(b uint8) {
+	l.x = int((b >> 3) & 0b111)
+	l.y = int((b) & 0b111)
+}
+
+// Transforms a PGN to a byte
+func pgnToByte(pgn string) uint8 {
+	l := Location{}
+	l.FromPGN(pgn)
+	return l.toByte()
+}
+
+// Holds a possible move and changes to board state
+type PossibleMove struct {
+	score float64
+
+	piece   int
+	end_pos Location
+
+	castle   bool
+	spiece   int
+	send_pos Location
+
+	enpassant uint8
+
+	target uint8
+
+	wK bool
+	wQ bool
+	bK bool
+	bQ bool
+
+	promote    bool
+	promote_to byte
+
+	invalid bool
+}
+
+type Piece uint8
+
+// Index of the moving piece
+func (pm *PossibleMove) Piece() int {
+	return pm.piece
+}
+
+// Destination of the moving piece
+func (pm *PossibleMove) EndPos() Location {
+	return pm.end_pos
+}
+
+// Returns TRUE if the move captures a piece
+func (pm *PossibleMove) Capture() bool {
+	return pm.target != 0
+}
+
+// Piece a promoting pawn turns into (q, r, b or n)
+func (pm *PossibleMove) PromoteTo() byte {
+	return pm.promote_to
+}
+
+// Returns TRUE if the move does not hold a valid movement
+func (pm *PossibleMove) Invalid() bool {
+	return pm.invalid
+}
+
+// Player piece array. Holds 16 regular pieces and 8 aditional pieces for promotion
+type PlayerPieces [24]Piece
+
+/*
+	Piece index to char representation map
+*/
+var indexPieceMap = map[int]string{
+	0:  "r",
+	1:  "n",
+	2:  "b",
+	3:  "q",
+	4:  "k",
+	5:  "b",
+	6:  "n",
+	7:  "r",
+	8:  "p",
+	9:  "p",
+	10: "p",
+	11: "p",
+	12: "p",
+	13: "p",
+	14: "p",
+	15: "p",
+}
+
+/*
+	Char representation to possible Piece index map
+*/
+var pieceIndexMap = map[byte][]int{
+	'r': {0, 7, 16, 17, 18, 19, 20, 21, 22, 23},
+	'n': {1, 6, 16, 17, 18, 19, 20, 21, 22, 23},
+	'b': {2, 5, 16, 17, 18, 19, 20, 21, 22, 23},
+	'q': {3, 16, 17, 18, 19, 20, 21, 22, 23},
+	'k': {4},
+	'p': {8, 9, 10, 11, 12, 13, 14, 15},
+}
+
+/*
+	Chessboard object
+*/
+type Chessboard struct {
+	white         PlayerPieces
+	whitePieceMap map[int]string
+
+	black         PlayerPieces
+	blackPieceMap map[int]string
+
+	toMove bool
+
+	wK bool
+	wQ bool
+	bK bool
+	bQ bool
+
+	enpassant uint8
+
+	// Halfmove clock (plies since the last capture or pawn move) and fullmove number
+	mc     int
+	rounds int
+
+	// Zobrist hash and the en passant square included in it (0 for none)
+	hash    int64
+	hash_ep uint8
+
+	// Hashes of the positions of the game (and of the line being searched), the last one is the current position
+	history []int64
+}
+
+/*
+	Starts the chessboard at the start location
+*/
+func (c *Chessboard) Init() {
+
+	// Set castling to true
+	c.wK = true
+	c.wQ = true
+	c.bK = true
+	c.bQ = true
+
+	// White moves first
+	c.toMove = true
+	c.mc = 0
+	c.rounds = 1
+
+	// Create the aditional piece data
+	c.whitePieceMap = make(map[int]string, 8)
+	c.blackPieceMap = make(map[int]string, 8)
+
+	// Init white pieces
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 8; x++ {
+			l := Location{x: x, y: y}
+			c.white[y*8+x] = Piece(l.toByte())
+		}
+	}
+
+	// Init black pieces
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 8; x++ {
+			l := Location{x: x, y: 7 - y}
+			c.black[y*8+x] = Piece(l.toByte())
+		}
+	}
+
+	c.rehash()
+	c.history = []int64{c.hash}
+}
+
+
+//rnbqkbnr/pppppppp/11111111/11111111/11111111/11111111/PPPPPPPP/RNBQKBNR w KQkq - 0 1
+/*
+	Convert chessboard object to FEN string
+*/
+func (c *Chessboard) Fen() string {
+
+	// If there is a current en passant going on, add it to the FEN
+	enpassant := "-"
+	if (c.enpassant&(1<<7))>>7 == 1 {
+		move := Location{}
+		move.fromByte(c.enpassant)
+		enpassant = move.PGN()
+	}
+
+	// Create FEN string and insert castling rights
+	fen := []byte(
+		fmt.Sprintf("11111111/11111111/11111111/11111111/11111111/11111111/11111111/11111111 %s %s%s%s%s %s %d %d",
+			map[bool]string{true: "w", false: "b"}[c.toMove],
+
+			map[bool]string{true: "K", false: "-"}[c.wK],
+			map[bool]string{true: "Q", false: "-"}[c.wQ],
+
+			map[bool]string{true: "k", false: "-"}[c.bK],
+			map[bool]string{true: "q", false: "-"}[c.bQ],
+
+			enpassant,
+			c.mc,
+			c.rounds,
+		),
+	)
+
+	// Loop through all white pieces and Add them to the FEN
+	for idx, wp := range c.white {
+
+		//Check if piece is valid
+		if (wp>>7)&1 != 1 {
+			continue
+		}
+
+		//Create Location
+		l := Location{}
+		l.fromByte(uint8(wp))
+
+		// Get piece char
+		if idx > 15 {
+			fen[l.toFen()] = strings.ToUpper(c.whitePieceMap[idx])[0]
+		} else {
+			fen[l.toFen()] = strings.ToUpper(indexPieceMap[idx])[0]
+		}
+
+	}
+
+	// Loop through all black pieces and Add them to the FEN
+	for idx, wp := range c.black {
+
+		//Check if piece is valid
+		if (wp>>7)&1 != 1 {
+			continue
+		}
+
+		//Create Location
+		l := Location{}
+		l.fromByte(uint8(wp))
+
+		// Get piece char
+		if idx > 15 {
+			fen[l.toFen()] = (c.blackPieceMap[idx])[0]
+		} else {
+			fen[l.toFen()] = (indexPieceMap[idx])[0]
+		}
+	}
+	return string(fen)
+}
+
+// Load a board from a FEN string
+func (c *Chessboard) FromFen(fen string) {
+	c.white = PlayerPieces{}
+	c.black = PlayerPieces{}
+	c.whitePieceMap = make(map[int]string, 8)
+	c.blackPieceMap = make(map[int]string, 8)
+	cchar := 0
+	for i := 0; i < 64; i++ {
+		char := fen[cchar]
+		if char > 'a' && char < 'z' {
+			for _, v := range pieceIndexMap[char] {
+				if c.black[v] == 0 {
+					if v >= 16 {
+						c.blackPieceMap[v] = string(char)
+					}
+					l := Location{
+						x: i % 8,
+						y: 7 - i/8,
+					}
+					c.black[v] = Piece(l.toByte())
+					break
+				}
+
+			}
+		} else if char > 'A' && char < 'Z' {
+			lcchar := strings.ToLower(string(char))[0]
+			for _, v := range pieceIndexMap[lcchar] {
+				if c.white[v] == 0 {
+					if v >= 16 {
+						c.whitePieceMap[v] = string(lcchar)
+					}
+					l := Location{
+						x: i % 8,
+						y: 7 - i/8,
+					}
+					c.white[v] = Piece(l.toByte())
+					break
+				}
+			}
+		} else if char == '/' {
+			i--
+		} else {
+			jumps := int(char - '0')
+			i += jumps - 1
+		}
+		cchar++
+	}
+
+	// Side to move, castling rights and en passant square
+	fields := strings.Fields(fen[cchar:])
+	c.toMove = len(fields) < 1 || fields[0] == "w"
+
+	c.wK = false
+	c.wQ = false
+	c.bK = false
+	c.bQ = false
+	if len(fields) > 1 {
+		for _, char := range fields[1] {
+			switch char {
+			case 'K':
+				c.wK = true
+			case 'Q':
+				c.wQ = true
+			case 'k':
+				c.bK = true
+			case 'q':
+				c.bQ = true
+			}
+		}
+	}
+
+	c.enpassant = 0
+	if len(fields) > 2 && len(fields[2]) == 2 {
+		c.enpassant = pgnToByte(fields[2])
+	}
+
+	// Halfmove clock and fullmove number
+	c.mc = 0
+	c.rounds = 1
+	if len(fields) > 3 {
+		if mc, err := strconv.Atoi(fields[3]); err == nil && mc >= 0 {
+			c.mc = mc
+		}
+	}
+	if len(fields) > 4 {
+		if rounds, err := strconv.Atoi(fields[4]); err == nil && rounds > 0 {
+			c.rounds = rounds
+		}
+	}
+
+	c.placeCastlingRooks()
+	c.rehash()
+	c.history = []int64{c.hash}
+}
+
+/*
+	Castling moves the rooks at index 0 (queen side) and 7 (king side). Swaps the
+	rooks loaded from a FEN so the ones on the corners hold those indexes
+*/
+func (c *Chessboard) placeCastlingRooks() {
+	for _, team := range []bool{true, false} {
+		pieces := &c.black
+		y := 7
+		if team {
+			pieces = &c.white
+			y = 0
+		}
+		for _, idx := range []int{0, 7} {
+			corner := Piece((&Location{x: idx, y: y}).toByte())
+			for _, other := range []int{0, 7} {
+				if other != idx && pieces[other] == corner {
+					pieces[idx], pieces[other] = pieces[other], pieces[idx]
+				}
+			}
+		}
+	}
+}
+
+/*
+	Check if there are any pieces in the posisiton. Takes into account en passant (ghost piece).
+	bit 5 is 1 if piece is white 0 otherwise
+*/
+func (c *Chessboard) hasPieceInPosition(position uint8, enpassant bool) (bool, int) {
+
+	// Start with calculating En passant
+	if enpassant {
+		if position == c.enpassant {
+			hp, piece := c.hasPieceInPosition(position+1, false)
+			if !hp {
+				_, piece = c.hasPieceInPosition(position-1, false)
+			}
+			return true, piece
+		}
+	}
+
+	// Check all the white pieces
+	for idx, p := range c.white {
+		if position == uint8(p) {
+			return true, 1<<5 | idx
+		}
+	}
+
+	// Check all black pieces
+	for idx, p := range c.black {
+		if position == uint8(p) {
+			return true, idx
+		}
+	}
+
+	return false, 0
+}
+
+/*
+	Check if PIECE from TEAM attacks END_POS
+*/
+func (c *Chessboard) pieceAttacks(piece int, team bool, end_pos Location) bool {
+
+	// Get piece movement table offset
+	start_pos := Location{}
+	piecei := 0
+
+	if team {
+		start_pos.fromByte(uint8(c.white[piece]))
+
+		if piece > 15 {
+			piecei = pieceMap[c.whitePieceMap[int(piece)][0]]
+		} else {
+			piecei = pieceMap[indexPieceMap[int(piece)][0]]
+		}
+
+	} else {
+		start_pos.fromByte(uint8(c.black[piece]))
+
+		if piece > 15 {
+			p := c.blackPieceMap[int(piece)][0]
+			piecei = pieceMap[p]
+		} else {
+			p := indexPieceMap[int(piece)][0]
+			if p == 'p' {
+				p = 'P'
+			}
+			if p == 'k' {
+				p = 'K'
+			}
+			piecei = pieceMap[p]
+		}
+
+	}
+
+	// The king table holds the castling squares, but a king only attacks the squares next to it
+	if piece == 4 {
+		dx := end_pos.x - start_pos.x
+		dy := end_pos.y - start_pos.y
+		return dx >= -1 && dx <= 1 && dy >= -1 && dy <= 1 && (dx != 0 || dy != 0)
+	}
+
+	// Loop through movement table
+	ep := end_pos.toByte()
+	for moveLines := 0; moveLines < 8; moveLines++ {
+		for move := 0; move < 7; move++ {
+			value := moveset.Mset[piecei+56*start_pos.y+56*8*start_pos.x+moveLines*7+move]
+
+			// Value 0 is invalid movement
+			if value == 0 {
+				break
+			}
+
+			// If position is in the table
+			if (ep & 0b111111) == (value & 0b111111) {
+
+				// Special Pawn rules
+				if piece > 7 && piece < 16 {
+					if moveLines > 0 {
+						// Pawns only attack on >1 movelines
+						return true
+					} else {
+						return false
+					}
+				} else {
+					return true
+				}
+
+			} else {
+
+				// If movement blocked by piece
+				if c, _ := c.hasPieceInPosition((value&0b111111)|1<<7, false); c {
+					break
+				}
+			}
+
+		}
+	}
+	return false
+}
+
+/*
+	Check if square is attacked by TEAM_ATTACKING
+*/
+func (c *Chessboard) isSquareAttacked(team_attacking bool, loc Location) bool {
+	if team_attacking {
+		for idx, pos := range c.white {
+			if pos == 0 {
+				continue
+			}
+
+			attack := c.pieceAttacks(idx, team_attacking, loc)
+			if attack {
+				return true
+			}
+		}
+	} else {
+		for idx, pos := range c.black {
+			if pos == 0 {
+				continue
+			}
+
+			attack := c.pieceAttacks(idx, team_attacking, loc)
+			if attack {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+/*
+	Check if state is valid (king is not in check)
+*/
+func (c *Chessboard) VerifyState(team bool) bool {
+	if team {
+		kingloc := Location{}
+		kingloc.fromByte(uint8(c.white[4]))
+		return !c.isSquareAttacked(!team, kingloc)
+	} else {
+		kingloc := Location{}
+		kingloc.fromByte(uint8(c.black[4]))
+		return !c.isSquareAttacked(!team, kingloc)
+	}
+}
+
+
+// Returns TRUE if TEAM has a piece other than the king and pawns
+func (c *Chessboard) HasPieces(team bool) bool {
+	return c.NonPawnPieces(team) != 0
+}
+
+// Number of pieces of TEAM other than the king and pawns
+func (c *Chessboard) NonPawnPieces(team bool) int {
+	pieces := c.black
+	if team {
+		pieces = c.white
+	}
+	count := 0
+	for idx, pos := range pieces {
+		if pos != 0 && idx != 4 && (idx < 8 || idx > 15) {
+			count++
+		}
+	}
+	return count
+}
+
+// Duplicate chessboard
+func (c *Chessboard) Duplicate() Chessboard {
+
+	board := Chessboard{
+		white:     c.white,
+		black:     c.black,
+		toMove:    c.toMove,
+		wK:        c.wK,
+		wQ:        c.wQ,
+		bK:        c.bK,
+		bQ:        c.bQ,
+		enpassant: c.enpassant,
+		mc:        c.mc,
+		rounds:    c.rounds,
+		hash:      c.hash,
+		hash_ep:   c.hash_ep,
+	}
+
+	board.blackPieceMap = make(map[int]string)
+	for k, v := range c.blackPieceMap {
+		board.blackPieceMap[k] = v
+	}
+
+	board.whitePieceMap = make(map[int]string)
+	for k, v := range c.whitePieceMap {
+		board.whitePieceMap[k] = v
+	}
+
+	board.history = make([]int64, len(c.history))
+	copy(board.history, c.history)
+
+	return board
+}
+
+/*
+	Saves a state (does not create a new object in memory)
+*/
+func (c *Chessboard) SaveState(target *Chessboard) {
+
+	target.white = c.white
+	target.black = c.black
+	target.toMove = c.toMove
+	target.wK = c.wK
+	target.wQ = c.wQ
+	target.bK = c.bK
+	target.bQ = c.bQ
+	target.enpassant = c.enpassant
+	target.mc = c.mc
+	target.rounds = c.rounds
+	target.hash = c.hash
+	target.hash_ep = c.hash_ep
+
+	target.whitePieceMap = make(map[int]string, 8)
+	target.blackPieceMap = make(map[int]string, 8)
+
+	for k, v := range c.blackPieceMap {
+		target.blackPieceMap[k] = v
+	}
+
+	for k, v := range c.whitePieceMap {
+		target.whitePieceMap[k] = v
+	}
+
+	// The history is shared. Boards saved during a search are played depth first,
+	// so a child only appends past the end of its parent's history
+	target.history = c.history
+}
+
+// Returns TRUE if it is white's turn to move
+func (c *Chessboard) ToMove() bool {
+	return c.toMove
+}
+
+// Number of plies since the last capture or pawn move
+func (c *Chessboard) HalfmoveClock() int {
+	return c.mc
+}
+
+// Number of the current move, starts at 1 and grows after every black move
+func (c *Chessboard) FullmoveNumber() int {
+	return c.rounds
+}
+
+// Returns TRUE if the last 100 plies had no captures or pawn moves (fifty-move rule)
+func (c *Chessboard) FiftyMoveRule() bool {
+	return c.mc >= 100
+}
+
+// Returns the location of PIECE from TEAM
+func (c *Chessboard) PieceLocation(piece int, team bool) Location {
+	l := Location{}
+	if team {
+		l.fromByte(uint8(c.white[piece]))
+	} else {
+		l.fromByte(uint8(c.black[piece]))
+	}
+	return l
+}
+
+// Returns the lowercase char representation of PIECE from TEAM
+func (c *Chessboard) PieceName(piece int, team bool) string {
+	if piece > 15 {
+		if team {
+			return c.whitePieceMap[piece]
+		}
+		return c.blackPieceMap[piece]
+	}
+	return indexPieceMap[piece]
+}
+
+// Finds the index of the piece of type PIECE_RUNE from TEAM at POS
+func (c *Chessboard) FindPiece(team bool, pieceRune byte, pos Location) (int, bool) {
+	for _, v := range pieceIndexMap[pieceRune] {
+		if team {
+			if c.white[v] == Piece(pos.toByte()) {
+				if v > 15 && c.whitePieceMap[v] != string(pieceRune) {
+					continue
+				}
+				return v, true
+			}
+		} else {
+			if c.black[v] == Piece(pos.toByte()) {
+				if v > 15 && c.blackPieceMap[v] != string(pieceRune) {
+					continue
+				}
+				return v, true
+			}
+		}
+	}
+	return -1, false
+}
+
+// Counts the pieces on the board for each team
+func (c *Chessboard) PieceCount() (int, int) {
+	pcw := 0
+	pcb := 0
+
+	for _, p := range c.white {
+		if p != 0 {
+			pcw++
+		}
+	}
+
+	for _, p := range c.black {
+		if p != 0 {
+			pcb++
+		}
+	}
+	return pcw, pcb
+}
+
+/*
+	Returns TRUE if neither team can checkmate with the pieces left: bare kings,
+	a single minor piece or bishops that all stand on squares of the same color
+*/
+func (c *Chessboard) InsufficientMaterial() bool {
+	minors := 0
+	knights := 0
+	bishop_colors := [2]int{}
+
+	for _, team := range []bool{true, false} {
+		pieces := c.black
+		if team {
+			pieces = c.white
+		}
+		for idx, p := range pieces {
+			if p == 0 || idx == 4 {
+				continue
+			}
+			switch c.PieceName(idx, team) {
+			case "n":
+				minors++
+				knights++
+			case "b":
+				minors++
+				l := Location{}
+				l.fromByte(uint8(p))
+				bishop_colors[(l.x+l.y)%2]++
+			default:
+				// Pawns, rooks and queens can always mate
+				return false
+			}
+		}
+	}
+
+	if minors <= 1 {
+		return true
+	}
+	return knights == 0 && (bishop_colors[0] == 0 || bishop_colors[1] == 0)
+}
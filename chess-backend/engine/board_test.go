@@ -0,0 +1,138 @@
+package engine
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestFenClocks(t *testing.T) {
+	board := Chessboard{}
+	board.FromFen("4k3/8/8/8/8/8/8/4K2R b K - 37 42")
+	if board.HalfmoveClock() != 37 || board.FullmoveNumber() != 42 {
+		t.Errorf("clocks = %d %d, want 37 42", board.HalfmoveClock(), board.FullmoveNumber())
+	}
+	if fen := board.Fen(); !strings.HasSuffix(fen, " 37 42") {
+		t.Errorf("Fen = %s, want the clocks 37 42", fen)
+	}
+
+	// Missing clocks default to 0 1
+	board.FromFen("4k3/8/8/8/8/8/8/4K2R w K -")
+	if board.HalfmoveClock() != 0 || board.FullmoveNumber() != 1 {
+		t.Errorf("clocks = %d %d, want 0 1", board.HalfmoveClock(), board.FullmoveNumber())
+	}
+}
+
+func TestMakeMoveClocks(t *testing.T) {
+	board := Chessboard{}
+	board.FromFen(START_FEN)
+
+	for _, tc := range []struct {
+		move     string
+		halfmove int
+		fullmove int
+	}{
+		{"g1f3", 1, 1},
+		{"g8f6", 2, 2},
+		{"e2e4", 0, 2},
+		{"f6e4", 0, 3},
+		{"f1e2", 1, 3},
+		{"b8c6", 2, 4},
+		{"e1g1", 3, 4},
+	} {
+		if !board.MakeUCIMove(tc.move) {
+			t.Fatalf("%s is not legal in %s", tc.move, board.Fen())
+		}
+		if board.HalfmoveClock() != tc.halfmove || board.FullmoveNumber() != tc.fullmove {
+			t.Errorf("after %s clocks = %d %d, want %d %d", tc.move, board.HalfmoveClock(), board.FullmoveNumber(), tc.halfmove, tc.fullmove)
+		}
+	}
+}
+
+func TestMakeUnsafeMoveClocks(t *testing.T) {
+	board := Chessboard{}
+	board.FromFen("4k3/8/8/8/8/8/4P3/R3K3 b Q - 10 20")
+
+	var next Chessboard
+	board.SaveState(&next)
+	next.MakeUnsafeMove(moveByUCI(t, &board, "e8d7"), false)
+	if next.HalfmoveClock() != 11 || next.FullmoveNumber() != 21 {
+		t.Errorf("after a king move clocks = %d %d, want 11 21", next.HalfmoveClock(), next.FullmoveNumber())
+	}
+
+	next.toMove = true
+	var after Chessboard
+	next.SaveState(&after)
+	after.MakeUnsafeMove(moveByUCI(t, &next, "e2e4"), true)
+	if after.HalfmoveClock() != 0 || after.FullmoveNumber() != 21 {
+		t.Errorf("after a pawn move clocks = %d %d, want 0 21", after.HalfmoveClock(), after.FullmoveNumber())
+	}
+}
+
+func TestFiftyMoveRule(t *testing.T) {
+	board := Chessboard{}
+	board.FromFen("7k/8/8/8/8/8/8/KQ6 b - - 99 80")
+	if board.FiftyMoveRule() {
+		t.Fatal("fifty-move rule applied after 99 plies")
+	}
+
+	// Every black move is reversible, so the queen is worth nothing
+	e := NewEngine()
+	states := 0
+	score, _ := e.Minimax(&board, 2, math.Inf(-1), math.Inf(+1), false, &states)
+	if score != 0 {
+		t.Errorf("minimax = %f, want a draw", score)
+	}
+
+	if !board.MakeUCIMove("h8g8") || !board.FiftyMoveRule() {
+		t.Errorf("fifty-move rule not applied after 100 plies")
+	}
+}
+
+func TestInsufficientMaterial(t *testing.T) {
+	for _, tc := range []struct {
+		fen  string
+		dead bool
+	}{
+		{"4k3/8/8/8/8/8/8/4K3 w - - 0 1", true},
+		{"4k3/8/8/8/8/8/8/2B1K3 w - - 0 1", true},
+		{"4k3/8/8/8/8/8/8/1N2K3 w - - 0 1", true},
+		{"4kn2/8/8/8/8/8/8/4K3 w - - 0 1", true},
+		{"2b1k3/8/8/8/8/8/8/3BK3 w - - 0 1", true},
+		{"2b1k3/8/8/8/8/8/8/2B1KB2 w - - 0 1", false},
+		{"4k3/8/8/8/8/8/8/2BBK3 w - - 0 1", false},
+		{"2b1k3/8/8/8/8/8/8/2B1K3 w - - 0 1", false},
+		{"4kb2/8/8/8/8/8/8/2B1K3 w - - 0 1", true},
+		{"4kn2/8/8/8/8/8/8/1N2K3 w - - 0 1", false},
+		{"4kn2/8/8/8/8/8/8/2B1K3 w - - 0 1", false},
+		{"4k3/8/8/8/8/8/8/1NN1K3 w - - 0 1", false},
+		{"4k3/8/8/8/8/8/4P3/4K3 w - - 0 1", false},
+		{"4k3/8/8/8/8/8/8/R3K3 w - - 0 1", false},
+		{"4k3/8/8/8/8/8/8/3QK3 w - - 0 1", false},
+		// Promoted bishop on the same color as the other one
+		{"2b1k3/8/8/8/8/8/8/3BK2B w - - 0 1", true},
+	} {
+		board := Chessboard{}
+		board.FromFen(tc.fen)
+		if dead := board.InsufficientMaterial(); dead != tc.dead {
+			t.Errorf("InsufficientMaterial(%s) = %t, want %t", tc.fen, dead, tc.dead)
+		}
+	}
+}
+
+func TestMinimaxInsufficientMaterial(t *testing.T) {
+	board := Chessboard{}
+	board.FromFen("4k3/8/8/8/8/8/8/2B1K3 w - - 0 1")
+
+	e := NewEngine()
+	states := 0
+	if score, _ := e.Minimax(&board, 3, math.Inf(-1), math.Inf(+1), true, &states); score != 0 {
+		t.Errorf("minimax = %f, want a draw", score)
+	}
+
+	// K+B v K, every move reaches the horizon in a dead position
+	board.FromFen("4k3/8/8/8/8/8/3K4/3B4 b - - 0 1")
+	if score, _ := e.Minimax(&board, 1, math.Inf(-1), math.Inf(+1), false, &states); score != 0 {
+		t.Errorf("minimax at the horizon = %f, want a draw", score)
+	}
+}
@@ -0,0 +1,206 @@
+// Package engine holds the chessboard, the move rules and the minimax bot
+package engine
+
+import (
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Minimax initial depth
+const BOT_MINIMAX_DEPTH = 5
+
+// Search threads of a new engine
+const BOT_THREADS = 1
+
+// Points a root move can lose against the best one and still be played by the bot
+const BOT_VARIETY = 10
+
+// Selective search features, each one can be turned off to compare the engine with and without it
+type SearchOptions struct {
+	// Principal variation search, moves after the first are tried with a zero window
+	PVS bool
+	// Null-move pruning
+	NullMove bool
+	// Late move reductions
+	LMR bool
+	// Aspiration windows around the score of the last iteration
+	Aspiration bool
+	// Moves that give check are searched one ply deeper
+	CheckExtension bool
+	// Pawn pushes to the 7th rank are searched one ply deeper
+	PawnExtension bool
+	// The hash move is searched one ply deeper when every other move is clearly worse
+	SingularExtension bool
+}
+
+// Every feature on
+func DefaultSearchOptions() SearchOptions {
+	return SearchOptions{
+		PVS: true, NullMove: true, LMR: true, Aspiration: true,
+		CheckExtension: true, PawnExtension: true, SingularExtension: true,
+	}
+}
+
+/*
+	Engine object. Holds the search state so that several engines can be
+	embedded in the same program without sharing tables. An engine runs one
+	search at a time, concurrent games must use an engine each
+*/
+type Engine struct {
+	// Minimax depth used by the bot
+	Depth int
+
+	// Threads searching each position. The extra threads are helpers that
+	// search the same position and share what they find through the transposition table
+	Threads int
+
+	// Selective search features in use
+	Options SearchOptions
+
+	// Best root moves searched by Think, each with its score and line
+	MultiPV int
+
+	// Root moves scored less than Variety points away from the best one may
+	// be played instead of it, picked at random, and reported with their own
+	// score. 0 always plays the best move, MultiPV turns it off
+	Variety float64
+	// No randomness and no helper threads, a search limited by depth always gives the same result
+	Deterministic bool
+	// Random generator of the engine, see Seed
+	rng *rand.Rand
+
+	// Transposition Table
+	transposition_table *TranspositionTable
+
+	// Position of the last search, a new root starts a new table age
+	root int64
+
+	// Killer moves and history of the running search
+	ordering moveOrdering
+	// Plies where the null move is being searched
+	null_move [MAX_PLY]bool
+	// Principal variation of the running search
+	pv pvTable
+	// Root moves left out of the search, already reported by MultiPV
+	excluded []PossibleMove
+	// Plies each line was extended by, up to every ply of the running search
+	extensions [MAX_PLY]int
+	// Hash moves left out of the search of each ply to check if they are singular
+	singular        [MAX_PLY]TranspositionEntry
+	singular_search [MAX_PLY]bool
+	// Cutoff counters of the last call to Minimax
+	stats SearchStats
+
+	// Set to 1 to interrupt the running search
+	stop int32
+}
+
+// Creates a new engine with an empty transposition table of TT_DEFAULT_MB
+func NewEngine() *Engine {
+	e := &Engine{
+		Depth:               BOT_MINIMAX_DEPTH,
+		Threads:             BOT_THREADS,
+		MultiPV:             1,
+		Options:             DefaultSearchOptions(),
+		transposition_table: NewTranspositionTable(TT_DEFAULT_MB),
+	}
+	e.Seed(time.Now().UnixNano())
+	return e
+}
+
+// Seeds the random generator of the engine, the same seed gives the same choices
+func (e *Engine) Seed(seed int64) {
+	e.rng = rand.New(rand.NewSource(seed))
+}
+
+// Picks one of MOVES with the random generator of the engine
+func (e *Engine) RandomMove(moves []PossibleMove) PossibleMove {
+	if e.Deterministic {
+		return moves[0]
+	}
+	return moves[e.rng.Intn(len(moves))]
+}
+
+// Replaces the transposition table with an empty one of MB megabytes
+func (e *Engine) SetHashSize(mb int) {
+	e.transposition_table = NewTranspositionTable(mb)
+}
+
+// Forgets everything learned in the previous game
+func (e *Engine) NewGame() {
+	e.transposition_table.Clear()
+	e.ordering = moveOrdering{}
+	e.root = 0
+}
+
+// Returns the cutoff counters of the calls to Minimax since the last iteration of Think started
+func (e *Engine) Stats() SearchStats {
+	return e.stats
+}
+
+// Returns the principal variation of the last call to Minimax, the line it expects to be played
+func (e *Engine) PV() []PossibleMove {
+	return e.pv.line()
+}
+
+// Calculate the best movement for a TEAM
+func (e *Engine) Minimax(c *Chessboard, depth int, alfa float64, beta float64, team bool, num_states *int) (float64, PossibleMove) {
+	// Iterations on the same position belong to the same search
+	if c.hash != e.root {
+		e.root = c.hash
+		e.transposition_table.newSearch()
+		e.ordering.newSearch()
+	}
+
+	// Lazy SMP, helpers search their own copy of the board until the main search finishes
+	helpers := []*Engine{}
+	helper_states := make([]int, e.Threads)
+	var wg sync.WaitGroup
+	for i := 1; i < e.Threads && !e.Deterministic; i++ {
+		helper := &Engine{Depth: e.Depth, Options: e.Options, transposition_table: e.transposition_table}
+		helpers = append(helpers, helper)
+		board := c.Duplicate()
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// Half of the helpers look one ply deeper to reach other parts of the tree first
+			helper.minimax(&board, depth+i%2, 0, alfa, beta, team, &helper_states[i])
+		}(i)
+	}
+
+	score, pm := e.minimax(c, depth, 0, alfa, beta, team, num_states)
+
+	for _, helper := range helpers {
+		helper.Stop()
+	}
+	wg.Wait()
+	for _, states := range helper_states {
+		*num_states += states
+	}
+	for _, helper := range helpers {
+		e.stats.add(helper.stats)
+	}
+	return score, pm
+}
+
+// Hashes a Board into an int64
+func (e *Engine) ZobristHash(c *Chessboard) int64 {
+	return c.Hash()
+}
+
+// Interrupts the running search. The interrupted search returns an invalid move
+func (e *Engine) Stop() {
+	atomic.StoreInt32(&e.stop, 1)
+}
+
+// Allows new searches to run after a Stop
+func (e *Engine) ClearStop() {
+	atomic.StoreInt32(&e.stop, 0)
+}
+
+// Returns TRUE if the search was interrupted
+func (e *Engine) Stopped() bool {
+	return atomic.LoadInt32(&e.stop) == 1
+}
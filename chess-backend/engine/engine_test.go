@@ -0,0 +1,141 @@
+package engine
+
+import (
+	"math"
+	"sync"
+	"testing"
+)
+
+// Plays several bot games at the same time, one engine each. Run with -race
+// to check that the games share no search state
+func TestConcurrentGames(t *testing.T) {
+	openings := []string{
+		START_FEN,
+		"r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3",
+		"rnbqkb1r/pppppppp/5n2/8/3P4/8/PPP1PPPP/RNBQKBNR w KQkq - 1 2",
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+	}
+	plies := 12
+	if testing.Short() {
+		plies = 4
+	}
+
+	var wg sync.WaitGroup
+	for _, fen := range openings {
+		wg.Add(1)
+		go func(fen string) {
+			defer wg.Done()
+
+			e := NewEngine()
+			e.Depth = 2
+			board := Chessboard{}
+			board.FromFen(fen)
+			for ply := 0; ply < plies; ply++ {
+				team := board.ToMove()
+				if len(board.PossibleMoves(team)) == 0 {
+					return
+				}
+				states := 0
+				_, pm := e.Minimax(&board, e.Depth, math.Inf(-1), math.Inf(+1), team, &states)
+				if valid, _ := board.MakeMove(uint8(pm.Piece()), team, pm.EndPos(), pm.PromoteTo()); !valid {
+					t.Errorf("%s: bot played the invalid move %s at ply %d", fen, board.MoveUCI(pm), ply)
+					return
+				}
+			}
+		}(fen)
+	}
+	wg.Wait()
+}
+
+// Deterministic engines give the same move, score and states, even with threads and variety
+func TestDeterministic(t *testing.T) {
+	board := Chessboard{}
+	board.FromFen("r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3")
+
+	var first SearchInfo
+	for i := 0; i < 3; i++ {
+		e := NewEngine()
+		e.Threads = 4
+		e.Variety = 50
+		e.Deterministic = true
+		info := e.Think(&board, SearchLimits{Depth: 4}, nil)
+		if i == 0 {
+			first = info
+			continue
+		}
+		if board.MoveUCI(info.Move) != board.MoveUCI(first.Move) || info.Score != first.Score || info.States != first.States {
+			t.Errorf("search %d: %s %.2f in %d states, want %s %.2f in %d states", i, board.MoveUCI(info.Move), info.Score, info.States, board.MoveUCI(first.Move), first.Score, first.States)
+		}
+	}
+}
+
+// Variety only plays root moves close to the best one, and the same seed plays the same moves
+func TestVariety(t *testing.T) {
+	board := Chessboard{}
+	board.FromFen(START_FEN)
+	const depth = 3
+	const margin = 15
+
+	// Exact score of every root move
+	plain := NewEngine()
+	plain.Options = SearchOptions{}
+	scores := map[string]float64{}
+	best := math.Inf(-1)
+	for _, pm := range board.PossibleMoves(true) {
+		child := board.Duplicate()
+		child.MakeUnsafeMove(pm, true)
+		states := 1
+		score, _ := plain.minimax(&child, depth-1, 1, math.Inf(-1), math.Inf(+1), false, &states)
+		scores[board.MoveUCI(pm)] = score
+		best = math.Max(best, score)
+	}
+
+	played := map[string]bool{}
+	for seed := int64(1); seed <= 20; seed++ {
+		moves := []string{}
+		for i := 0; i < 2; i++ {
+			e := NewEngine()
+			e.Variety = margin
+			e.Seed(seed)
+			states := 0
+			_, pm := e.Minimax(&board, depth, math.Inf(-1), math.Inf(+1), true, &states)
+			moves = append(moves, board.MoveUCI(pm))
+		}
+		if moves[0] != moves[1] {
+			t.Errorf("seed %d played %s and %s", seed, moves[0], moves[1])
+		}
+		if score := scores[moves[0]]; score <= best-margin {
+			t.Errorf("seed %d played %s scored %.2f, best is %.2f", seed, moves[0], score, best)
+		}
+		played[moves[0]] = true
+	}
+	if len(played) < 2 {
+		t.Errorf("only %v was played", played)
+	}
+
+	// The move played is reported with its own score
+	for seed := int64(1); seed <= 20; seed++ {
+		e := NewEngine()
+		e.Options = SearchOptions{}
+		e.Variety = margin
+		e.Seed(seed)
+		states := 0
+		score, pm := e.Minimax(&board, depth, math.Inf(-1), math.Inf(+1), true, &states)
+		if move := board.MoveUCI(pm); score != scores[move] {
+			t.Errorf("seed %d played %s scored %.2f, reported %.2f", seed, move, scores[move], score)
+		}
+	}
+
+	// MultiPV reports the best moves, in order
+	for seed := int64(1); seed <= 5; seed++ {
+		e := NewEngine()
+		e.Options = SearchOptions{}
+		e.Variety = margin
+		e.MultiPV = 3
+		e.Seed(seed)
+		info := e.Think(&board, SearchLimits{Depth: depth}, nil)
+		if score := scores[board.MoveUCI(info.Lines[0].Move)]; score != best {
+			t.Errorf("seed %d first line %s scored %.2f, best is %.2f", seed, board.MoveUCI(info.Lines[0].Move), score, best)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package engine
+
+import (
+	"math"
+	"yrk06/chess-backend/moveset"
+)
+
+// Value per piece
+var pieceValue = map[int]int{
+	0: 500,
+	1: 300,
+	2: 300,
+	3: 900,
+	4: 42000,
+	5: 300,
+	6: 300,
+	7: 500,
+
+	8:  100,
+	9:  100,
+	10: 100,
+	11: 100,
+	12: 100,
+	13: 100,
+	14: 100,
+	15: 100,
+}
+
+
+// Maps from Piece index to Evaluation memory offset
+var evaluationPieceOffset = map[int]int{
+	0:  64 * 3,
+	1:  64 * 1,
+	2:  64 * 2,
+	3:  64 * 4,
+	4:  64 * 5,
+	5:  64 * 2,
+	6:  64 * 1,
+	7:  64 * 3,
+	8:  0,
+	9:  0,
+	10: 0,
+	11: 0,
+	12: 0,
+	13: 0,
+	14: 0,
+	15: 0,
+}
+
+/*
+	Evaluate board value
+*/
+func (c *Chessboard) Evaluate() float64 {
+	total := 0.0
+	wpiece := 0
+	bpiece := 0
+	for idx, loc := range c.white {
+		if loc == 0 {
+			continue
+		}
+		wpiece += 1
+		total += float64(pieceValue[idx])
+		if idx == 4 {
+			continue
+		}
+		if idx > 15 {
+			rune := c.whitePieceMap[idx][0]
+			realidx := pieceIndexMap[rune][0]
+			// Promoted pieces are worth the same as the regular ones
+			total += float64(pieceValue[realidx])
+			total += moveset.Pst[evaluationPieceOffset[realidx]+int((loc>>3)&0b111)+8*int((loc&0b111))]
+		} else {
+			total += moveset.Pst[evaluationPieceOffset[idx]+int((loc>>3)&0b111)+8*int((loc&0b111))]
+		}
+
+	}
+	for idx, loc := range c.black {
+		if loc == 0 {
+			continue
+		}
+		bpiece += 1
+		total -= float64(pieceValue[idx])
+		if idx == 4 {
+			continue
+		}
+		if idx > 15 {
+			rune := c.blackPieceMap[idx][0]
+			realidx := pieceIndexMap[rune][0]
+			// Promoted pieces are worth the same as the regular ones
+			total -= float64(pieceValue[realidx])
+			total -= moveset.Pst[evaluationPieceOffset[realidx]+int((loc>>3)&0b111)+56-8*int((loc&0b111))]
+		} else {
+			total -= moveset.Pst[evaluationPieceOffset[idx]+int((loc>>3)&0b111)+56-8*int((loc&0b111))]
+		}
+	}
+	if bpiece <= 5 || wpiece <= 5 {
+		if total < 0 {
+			loc := c.white[4]
+			total += moveset.Pst[evaluationPieceOffset[4]+int((loc>>3)&0b111)+8*int((loc&0b111))]
+
+			locb := c.black[4]
+			total -= math.Abs(float64((loc>>3)&0b111-(locb>>3)&0b111+(loc)&0b111-(locb)&0b111)) * math.Min(5-float64(wpiece), 0) * 85
+
+		} else if total > 0 {
+			loc := c.black[4]
+			total -= moveset.Pst[evaluationPieceOffset[4]+int((loc>>3)&0b111)+56-8*int((loc&0b111))]
+
+			locb := c.white[4]
+			total += math.Abs(float64((loc>>3)&0b111-(locb>>3)&0b111+(loc)&0b111-(locb)&0b111)) * math.Min(5-float64(bpiece), 0) * 85
+		}
+	} else {
+		loc := c.white[4]
+		total += moveset.Pst[evaluationPieceOffset[4]+64+int((loc>>3)&0b111)+8*int((loc&0b111))]
+
+		loc = c.black[4]
+		total -= moveset.Pst[evaluationPieceOffset[4]+64+int((loc>>3)&0b111)+56-8*int((loc&0b111))]
+	}
+	return total
+}
@@ -0,0 +1,21 @@
+package engine
+
+import "testing"
+
+// A promoted queen is worth the same as a queen that was always on the board
+func TestEvaluatePromotedPieces(t *testing.T) {
+	promoted := Chessboard{}
+	promoted.FromFen("7k/P7/8/8/8/8/8/K7 w - - 0 1")
+	for _, pm := range promoted.PossibleMoves(true) {
+		if pm.promote {
+			promoted.MakeUnsafeMove(pm, true)
+			break
+		}
+	}
+
+	queen := Chessboard{}
+	queen.FromFen("Q6k/8/8/8/8/8/8/K7 b - - 0 1")
+	if promoted.Evaluate() != queen.Evaluate() {
+		t.Errorf("promoted queen evaluated %.0f, queen %.0f", promoted.Evaluate(), queen.Evaluate())
+	}
+}
@@ -0,0 +1,76 @@
+package engine
+
+// Plies a line can be extended in total, so that checks can not extend it forever
+const MAX_EXTENSIONS = 16
+
+// Singular extensions: depth from which they are tried, how much shallower the
+// hash entry can be and how much worse every other move must be
+const SINGULAR_MIN_DEPTH = 6
+const SINGULAR_DEPTH_MARGIN = 3
+const SINGULAR_MARGIN = 50
+
+/*
+	Plies the move STATE of TEAM, already made on BOARD, is searched deeper:
+	one for a check, a pawn push to the 7th rank or a singular hash move.
+	Nothing once the line reached MAX_EXTENSIONS. Records the extensions of
+	the line for the next ply
+*/
+func (e *Engine) extension(board *Chessboard, state PossibleMove, team bool, ply int, gives_check bool, singular bool) int {
+	ext := 0
+	if singular {
+		ext = 1
+	} else if e.Options.CheckExtension && gives_check {
+		ext = 1
+	} else if e.Options.PawnExtension && !state.promote && board.PieceName(state.piece, team) == "p" {
+		// Pawns still on the board after the move only reach the 7th rank
+		if team && state.end_pos.y == 6 || !team && state.end_pos.y == 1 {
+			ext = 1
+		}
+	}
+
+	line := 0
+	if ply < MAX_PLY {
+		line = e.extensions[ply]
+	}
+	if line+ext > MAX_EXTENSIONS {
+		ext = 0
+	}
+	if ext > 0 {
+		e.stats.Extensions++
+		if singular {
+			e.stats.SingularExtensions++
+		}
+	}
+	if ply+1 < MAX_PLY {
+		e.extensions[ply+1] = line + ext
+	}
+	return ext
+}
+
+/*
+	Returns TRUE if the move of the hash entry HASH of TEAM is singular: every
+	other move, searched to half the DEPTH, scores SINGULAR_MARGIN points worse
+	than the entry. Only for entries that tell the move is at least that good
+*/
+func (e *Engine) singularMove(c *Chessboard, depth int, ply int, hash TranspositionEntry, team bool, num_states *int) bool {
+	score := scoreFromTT(hash.score, ply)
+	if IsMate(score) || ply >= MAX_PLY {
+		return false
+	}
+	if team && hash.flag == TT_UPPER || !team && hash.flag == TT_LOWER {
+		return false
+	}
+
+	e.singular[ply] = hash
+	e.singular_search[ply] = true
+	defer func() { e.singular_search[ply] = false }()
+
+	if team {
+		singular_beta := score - SINGULAR_MARGIN
+		value, _ := e.minimax(c, (depth-1)/2, ply, singular_beta-PVS_WINDOW, singular_beta, team, num_states)
+		return value < singular_beta
+	}
+	singular_alfa := score + SINGULAR_MARGIN
+	value, _ := e.minimax(c, (depth-1)/2, ply, singular_alfa, singular_alfa+PVS_WINDOW, team, num_states)
+	return value > singular_alfa
+}
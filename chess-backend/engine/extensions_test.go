@@ -0,0 +1,115 @@
+package engine
+
+import "testing"
+
+// Extension of the move UCI of the side to move in FEN
+func moveExtension(t *testing.T, e *Engine, fen string, uci string) int {
+	t.Helper()
+	board := Chessboard{}
+	board.FromFen(fen)
+	team := board.toMove
+	pm := moveByUCI(t, &board, uci)
+	child := board.Duplicate()
+	child.MakeUnsafeMove(pm, team)
+	return e.extension(&child, pm, team, 0, !child.VerifyState(!team), false)
+}
+
+func TestPawnExtension(t *testing.T) {
+	cases := []struct {
+		fen  string
+		uci  string
+		want int
+	}{
+		{"k7/8/4P3/8/8/8/8/4K3 w - - 0 1", "e6e7", 1},
+		{"4k3/8/8/8/8/3p4/8/K7 b - - 0 1", "d3d2", 1},
+		{"k7/8/8/4P3/8/8/8/4K3 w - - 0 1", "e5e6", 0},
+		// Promotions are not pushes to the 7th
+		{"k7/4P3/8/8/8/8/8/4K3 w - - 0 1", "e7e8q", 0},
+		{"k7/8/4R3/8/8/8/8/4K3 w - - 0 1", "e6e7", 0},
+	}
+	for _, tc := range cases {
+		e := NewEngine()
+		e.Options = SearchOptions{PawnExtension: true}
+		if ext := moveExtension(t, e, tc.fen, tc.uci); ext != tc.want {
+			t.Errorf("%s in %s extended %d, want %d", tc.uci, tc.fen, ext, tc.want)
+		}
+	}
+
+	e := NewEngine()
+	e.Options = SearchOptions{}
+	if ext := moveExtension(t, e, "k7/8/4P3/8/8/8/8/4K3 w - - 0 1", "e6e7"); ext != 0 {
+		t.Errorf("push to the 7th extended %d with the extension off", ext)
+	}
+}
+
+func TestExtensionCap(t *testing.T) {
+	board := Chessboard{}
+	board.FromFen("k7/8/8/8/8/8/8/1R4K1 w - - 0 1")
+	pm := moveByUCI(t, &board, "b1a1")
+	board.MakeUnsafeMove(pm, true)
+
+	// Every move of a long line gives check, only MAX_EXTENSIONS are extended
+	e := NewEngine()
+	e.Options = SearchOptions{CheckExtension: true}
+	total := 0
+	const plies = MAX_EXTENSIONS + 10
+	for ply := 0; ply < plies; ply++ {
+		total += e.extension(&board, pm, true, ply, true, false)
+	}
+	if total != MAX_EXTENSIONS || e.extensions[plies] != MAX_EXTENSIONS {
+		t.Errorf("line extended %d plies, %d recorded, want %d", total, e.extensions[plies], MAX_EXTENSIONS)
+	}
+
+	// Without the option a check is not extended
+	e = NewEngine()
+	e.Options = SearchOptions{}
+	if ext := e.extension(&board, pm, true, 0, true, false); ext != 0 {
+		t.Errorf("check extended %d with the extension off", ext)
+	}
+}
+
+func TestSingularMove(t *testing.T) {
+	cases := []struct {
+		fen  string
+		want bool
+	}{
+		// Only taking the queen keeps white from losing
+		{"6k1/5ppp/8/3q4/8/8/5PPP/3R2K1 w - - 0 1", true},
+		// Many moves are about as good
+		{START_FEN, false},
+	}
+	for _, tc := range cases {
+		board := Chessboard{}
+		board.FromFen(tc.fen)
+		e := NewEngine()
+		e.Deterministic = true
+		e.Think(&board, SearchLimits{Depth: 4}, nil)
+		hash, ok := e.transposition_table.probe(board.hash)
+		if !ok || hash.piece < 0 {
+			t.Fatalf("%s: no hash move", tc.fen)
+		}
+
+		states := 0
+		if singular := e.singularMove(&board, SINGULAR_MIN_DEPTH, 1, hash, true, &states); singular != tc.want {
+			t.Errorf("%s: singular %t, want %t", tc.fen, singular, tc.want)
+		}
+		if e.singular_search[1] {
+			t.Errorf("%s: singular search left on", tc.fen)
+		}
+	}
+}
+
+func TestSingularExtension(t *testing.T) {
+	board := Chessboard{}
+	board.FromFen("8/5pk1/6p1/8/3R4/6P1/5PK1/2r5 w - - 0 1")
+
+	for _, on := range []bool{false, true} {
+		e := NewEngine()
+		e.Deterministic = true
+		e.Options.SingularExtension = on
+		info := e.Think(&board, SearchLimits{Depth: SINGULAR_MIN_DEPTH + 1}, nil)
+		if singular := info.Stats.SingularExtensions; on && singular == 0 || !on && singular != 0 {
+			t.Errorf("singular extension %t: %d singular moves extended", on, singular)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package engine
+
+import "math"
+
+/*
+	Score of mating at the root. A mate PLY plies away from the root scores
+	MATE_SCORE - PLY (white mates) or -(MATE_SCORE - PLY) (black mates), so
+	that closer mates are preferred and the score does not depend on the depth
+	of the search that found it
+*/
+const MATE_SCORE = 100000
+
+// Scores further from zero than MATE_BOUND are mates
+const MATE_BOUND = MATE_SCORE - 2*MAX_PLY
+
+// Score of TEAM being checkmated PLY plies away from the root
+func matedScore(team bool, ply int) float64 {
+	if team {
+		return -float64(MATE_SCORE - ply)
+	}
+	return float64(MATE_SCORE - ply)
+}
+
+// Returns TRUE if SCORE is a mate
+func IsMate(score float64) bool {
+	return !math.IsInf(score, 0) && math.Abs(score) > MATE_BOUND
+}
+
+/*
+	Moves to mate of a root SCORE, counted in moves of the side that mates.
+	Positive when white mates, negative when black mates and 0 if SCORE is not a mate
+*/
+func MateIn(score float64) int {
+	if !IsMate(score) {
+		return 0
+	}
+	plies := MATE_SCORE - int(math.Abs(score))
+	moves := (plies + 1) / 2
+	if score < 0 {
+		return -moves
+	}
+	return moves
+}
+
+/*
+	The transposition table stores mates as the distance from the position
+	and not from the root, so that an entry can be used PLY plies away from
+	the root of any search
+*/
+func scoreToTT(score float64, ply int) float64 {
+	if !IsMate(score) {
+		return score
+	}
+	if score > 0 {
+		return score + float64(ply)
+	}
+	return score - float64(ply)
+}
+
+// Mate distance from the root of a SCORE read from the transposition table PLY plies away from the root
+func scoreFromTT(score float64, ply int) float64 {
+	if !IsMate(score) {
+		return score
+	}
+	if score > 0 {
+		return score - float64(ply)
+	}
+	return score + float64(ply)
+}
@@ -0,0 +1,53 @@
+package engine
+
+import "testing"
+
+func TestMateIn(t *testing.T) {
+	for _, tc := range []struct {
+		score float64
+		moves int
+	}{
+		{MATE_SCORE - 1, 1},
+		{MATE_SCORE - 3, 2},
+		{MATE_SCORE - 4, 2},
+		{-(MATE_SCORE - 2), -1},
+		{-(MATE_SCORE - 5), -3},
+		{MATE_BOUND, 0},
+		{950, 0},
+	} {
+		if moves := MateIn(tc.score); moves != tc.moves {
+			t.Errorf("MateIn(%.0f) = %d, want %d", tc.score, moves, tc.moves)
+		}
+	}
+
+	// Stored as the distance from the position and read back as the distance from the root
+	for _, score := range []float64{MATE_SCORE - 7, -(MATE_SCORE - 7), 123} {
+		stored := scoreToTT(score, 4)
+		if got := scoreFromTT(stored, 4); got != score {
+			t.Errorf("score %.0f read back as %.0f", score, got)
+		}
+		if got := scoreFromTT(stored, 2); IsMate(score) && MateIn(got) == MateIn(score) {
+			t.Errorf("score %.0f read two plies closer to the root as the same mate", score)
+		}
+	}
+}
+
+func TestMateDistance(t *testing.T) {
+	board := Chessboard{}
+	board.FromFen("7k/8/8/8/8/8/8/RR4K1 w - - 0 1")
+
+	// Every iteration that sees the mate reports the same distance, even with the table of the previous ones
+	e := NewEngine()
+	e.Think(&board, SearchLimits{Depth: 6}, func(info SearchInfo) {
+		if info.Depth >= 3 && (info.Mate != 2 || info.Score != MATE_SCORE-3) {
+			t.Errorf("depth %d: mate %d with %.0f, want mate 2 with %d", info.Depth, info.Mate, info.Score, MATE_SCORE-3)
+		}
+	})
+
+	// The side getting mated
+	board.FromFen("7k/R7/8/8/8/8/8/1R4K1 b - - 0 1")
+	info := e.Think(&board, SearchLimits{Depth: 4}, nil)
+	if info.Mate != 1 {
+		t.Errorf("black to move: mate %d, want white mating in 1", info.Mate)
+	}
+}
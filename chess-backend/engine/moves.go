@@ -0,0 +1,1106 @@
+package engine
+
+import (
+	"log"
+	"yrk06/chess-backend/moveset"
+)
+
+/*
+	Attemps to move a piece and returns TRUE if move is valid
+*/
+func (c *Chessboard) MakeMove(piece uint8, team bool, end_pos Location, promote_to byte) (bool, uint8) {
+
+	// Setup Vars
+	start_pos := Location{}
+	piecei := 0
+
+	if team {
+		start_pos.fromByte(uint8(c.white[piece]))
+
+		if piece > 15 {
+			piecei = pieceMap[c.whitePieceMap[int(piece)][0]]
+		} else {
+			piecei = pieceMap[indexPieceMap[int(piece)][0]]
+		}
+
+	} else {
+		start_pos.fromByte(uint8(c.black[piece]))
+
+		if piece > 15 {
+			p := c.blackPieceMap[int(piece)][0]
+			piecei = pieceMap[p]
+		} else {
+			p := indexPieceMap[int(piece)][0]
+			if p == 'p' {
+				p = 'P'
+			}
+			if p == 'k' {
+				p = 'K'
+			}
+			piecei = pieceMap[p]
+		}
+
+	}
+
+	// Control variables
+	valid := false
+	enpassant := false
+	enpassant_active := false
+	canAttack := true
+	promotion := false
+	promotion_idx := 0
+
+	// Check for castling
+	castling := false
+	if piece == 4 {
+		if team {
+			if c.wK {
+				if end_pos == (Location{x: 6, y: 0}) {
+
+					if c.isSquareAttacked(!team, start_pos) {
+						return false, 0
+					}
+					sq1 := Location{x: 5, y: 0}
+					if c, _ := c.hasPieceInPosition(sq1.toByte(), false); c {
+						return false, 0
+					}
+					if c.isSquareAttacked(!team, sq1) {
+						return false, 0
+					}
+
+					sq2 := Location{x: 6, y: 0}
+					if c, _ := c.hasPieceInPosition(sq2.toByte(), false); c {
+						return false, 0
+					}
+					if c.isSquareAttacked(!team, sq2) {
+						return false, 0
+					}
+
+					c.white[4] = Piece(end_pos.toByte())
+					c.white[7] = Piece(sq1.toByte())
+					castling = true
+					c.wK = false
+					c.wQ = false
+
+				}
+			}
+			if c.wQ {
+				if end_pos == (Location{x: 2, y: 0}) {
+
+					if c.isSquareAttacked(!team, start_pos) {
+						return false, 0
+					}
+					sq1 := Location{x: 1, y: 0}
+					if c, _ := c.hasPieceInPosition(sq1.toByte(), false); c {
+						return false, 0
+					}
+
+					sq2 := Location{x: 2, y: 0}
+					if c, _ := c.hasPieceInPosition(sq2.toByte(), false); c {
+						return false, 0
+					}
+					if c.isSquareAttacked(!team, sq2) {
+						return false, 0
+					}
+
+					sq3 := Location{x: 3, y: 0}
+					if c, _ := c.hasPieceInPosition(sq3.toByte(), false); c {
+						return false, 0
+					}
+					if c.isSquareAttacked(!team, sq3) {
+						return false, 0
+					}
+
+					c.white[4] = Piece(end_pos.toByte())
+					c.white[0] = Piece(sq3.toByte())
+					castling = true
+					c.wK = false
+					c.wQ = false
+
+				}
+			}
+
+		} else {
+			if c.bK {
+				if end_pos == (Location{x: 6, y: 7}) {
+
+					if c.isSquareAttacked(!team, start_pos) {
+						return false, 0
+					}
+					sq1 := Location{x: 5, y: 7}
+					if c, _ := c.hasPieceInPosition(sq1.toByte(), false); c {
+						return false, 0
+					}
+					if c.isSquareAttacked(!team, sq1) {
+						return false, 0
+					}
+
+					sq2 := Location{x: 6, y: 7}
+					if c, _ := c.hasPieceInPosition(sq2.toByte(), false); c {
+						return false, 0
+					}
+					if c.isSquareAttacked(!team, sq2) {
+						return false, 0
+					}
+
+					c.black[4] = Piece(end_pos.toByte())
+					c.black[7] = Piece(sq1.toByte())
+					castling = true
+					c.bK = false
+					c.bQ = false
+
+				}
+			}
+			if c.bQ {
+				if end_pos == (Location{x: 2, y: 7}) {
+
+					if c.isSquareAttacked(!team, start_pos) {
+						return false, 0
+					}
+					sq1 := Location{x: 1, y: 7}
+					if c, _ := c.hasPieceInPosition(sq1.toByte(), false); c {
+						return false, 0
+					}
+
+					sq2 := Location{x: 2, y: 7}
+					if c, _ := c.hasPieceInPosition(sq2.toByte(), false); c {
+						return false, 0
+					}
+					if c.isSquareAttacked(!team, sq2) {
+						return false, 0
+					}
+
+					sq3 := Location{x: 3, y: 7}
+					if c, _ := c.hasPieceInPosition(sq3.toByte(), false); c {
+						return false, 0
+					}
+					if c.isSquareAttacked(!team, sq3) {
+						return false, 0
+					}
+
+					c.black[4] = Piece(end_pos.toByte())
+					c.black[0] = Piece(sq3.toByte())
+					castling = true
+					c.bK = false
+					c.bQ = false
+
+				}
+			}
+		}
+	}
+	if castling {
+		c.advanceClocks(team, false)
+		c.toMove = !c.toMove
+		c.rehash()
+		c.recordPosition()
+		return true, 0
+	}
+
+	// A king can only move two squares by castling
+	if piece == 4 && (end_pos.x-start_pos.x > 1 || start_pos.x-end_pos.x > 1) {
+		return false, 0
+	}
+
+	// Check if piece can move there
+	ep := end_pos.toByte()
+	for moveLines := 0; moveLines < 8; moveLines++ {
+		for move := 0; move < 7; move++ {
+			value := moveset.Mset[piecei+56*start_pos.y+56*8*start_pos.x+moveLines*7+move]
+
+			if value == 0 {
+				break
+			}
+
+			// if position is valid
+			if (ep & 0b111111) == (value & 0b111111) {
+
+				// Special Pawn rules
+				if piece > 7 && piece < 16 {
+
+					if moveLines == 0 {
+
+						// check if doing En passant
+						if move == 1 {
+							l := Location{}
+							l.fromByte(moveset.Mset[piecei+56*start_pos.y+56*8*start_pos.x+moveLines*7])
+							c.enpassant = l.toByte()
+							enpassant_active = true
+						}
+						canAttack = false
+						valid = true
+					} else {
+
+						// Take en passant into account
+						if co, _ := c.hasPieceInPosition((value&0b111111)|1<<7, true); co {
+							valid = true
+							if c.enpassant != 0 {
+								enpassant = true
+							}
+
+						}
+					}
+
+					if end_pos.y == 7 && team {
+						promotion = true
+
+					}
+
+					if end_pos.y == 0 && !team {
+						promotion = true
+
+					}
+				} else {
+					valid = true
+				}
+
+			} else {
+				if c, _ := c.hasPieceInPosition((value&0b111111)|1<<7, false); c {
+					break
+				}
+			}
+
+		}
+		if valid {
+			break
+		}
+	}
+
+	if !valid {
+		return false, 0
+	}
+
+	// Check if piece can capture target (if there is a target)
+	target, target_p := c.hasPieceInPosition(ep, enpassant)
+	if target {
+		pieceTeam := target_p >> 5
+		// Cannot capture your own pieces
+		if pieceTeam == 1 && team {
+			return false, 0
+		}
+		if pieceTeam == 0 && !team {
+			return false, 0
+		}
+		if !canAttack {
+			return false, 0
+		}
+
+	}
+
+	// Make the Move and check if king is in check
+	if team {
+		c.white[piece] = Piece(ep)
+	} else {
+		c.black[piece] = Piece(ep)
+	}
+	oldbQ := c.bQ
+	oldbK := c.bK
+
+	oldwQ := c.wQ
+	oldwK := c.wK
+
+	// Capture piece
+	if target {
+		if team {
+			if target_p&0x1F == 0 {
+				c.bQ = false
+			}
+			if target_p&0x1F == 7 {
+				c.bK = false
+			}
+			c.black[target_p&0x1F] = 0
+		} else {
+			if target_p&0x1F == 0 {
+				c.wQ = false
+			}
+			if target_p&0x1F == 7 {
+				c.wK = false
+			}
+			c.white[target_p&0x1F] = 0
+		}
+	}
+
+	if !enpassant_active {
+		c.enpassant = 0
+	}
+
+	if promotion {
+		if team {
+			for i := 16; i < 24; i++ {
+				if c.white[i] == 0 {
+					c.whitePieceMap[i] = string(promote_to)
+					c.white[i] = c.white[piece]
+					c.white[piece] = 0
+					promotion_idx = i
+					break
+				}
+			}
+		} else {
+			for i := 16; i < 24; i++ {
+				if c.black[i] == 0 {
+					c.blackPieceMap[i] = string(promote_to)
+					c.black[i] = c.black[piece]
+					c.black[piece] = 0
+					promotion_idx = i
+					break
+				}
+			}
+		}
+
+	}
+
+	// Check if move was valid by check rules and rollback if invalid
+	if !c.VerifyState(team) {
+		if team {
+			c.white[piece] = Piece(start_pos.toByte())
+		} else {
+			c.black[piece] = Piece(start_pos.toByte())
+		}
+		if target {
+			if team {
+				if target_p&0xF == 0 {
+					c.bQ = oldbQ
+				}
+				if target_p&0xF == 7 {
+					c.bK = oldbK
+				}
+				c.black[target_p&0xF] = Piece(ep)
+			} else {
+				if target_p&0xF == 0 {
+					c.wQ = oldwQ
+				}
+				if target_p&0xF == 7 {
+					c.wK = oldwK
+				}
+				c.white[target_p&0xF] = Piece(ep)
+			}
+		}
+
+		if promotion {
+			if team {
+				c.white[promotion_idx] = 0
+			} else {
+				c.black[promotion_idx] = 0
+			}
+
+		}
+		return false, 0
+	}
+
+	// Remove checking rights
+	if piece == 4 {
+		if team {
+			c.wK = false
+			c.wQ = false
+		} else {
+			c.bK = false
+			c.bQ = false
+		}
+	}
+
+	// Moving a rook removes the castling rights on its side
+	if piece == 0 {
+		if team {
+			c.wQ = false
+		} else {
+			c.bQ = false
+		}
+	}
+	if piece == 7 {
+		if team {
+			c.wK = false
+		} else {
+			c.bK = false
+		}
+	}
+
+	truetargetp := 0
+	if target {
+		truetargetp = target_p | 1<<5
+	}
+
+	c.advanceClocks(team, target || (piece > 7 && piece < 16))
+	c.toMove = !c.toMove
+	c.rehash()
+	c.recordPosition()
+	return true, uint8(truetargetp)
+}
+
+/*
+	Attemps to move PIECE and returns a possible move struct
+*/
+func (c *Chessboard) TestMove(piece uint8, team bool, end_pos Location, promote_to byte) (bool, PossibleMove) {
+
+	// Setup Vars
+	start_pos := Location{}
+	piecei := 0
+
+	if team {
+		start_pos.fromByte(uint8(c.white[piece]))
+
+		if piece > 15 {
+			piecei = pieceMap[c.whitePieceMap[int(piece)][0]]
+		} else {
+			piecei = pieceMap[indexPieceMap[int(piece)][0]]
+		}
+
+	} else {
+		start_pos.fromByte(uint8(c.black[piece]))
+
+		if piece > 15 {
+			p := c.blackPieceMap[int(piece)][0]
+			piecei = pieceMap[p]
+		} else {
+			p := indexPieceMap[int(piece)][0]
+			if p == 'p' {
+				p = 'P'
+			}
+			if p == 'k' {
+				p = 'K'
+			}
+			piecei = pieceMap[p]
+		}
+
+	}
+
+	// Control variables
+	predicted_score := 0.0
+	valid := false
+	enpassant := false
+	enpassant_active := false
+	canAttack := true
+	promotion := false
+	promotion_idx := 0
+
+	// Check for castling
+	castling := false
+	cq := false
+	cep := Location{}
+	// Only a king on its starting square can castle
+	if piece == 4 && start_pos.x == 4 && start_pos.y == map[bool]int{true: 0, false: 7}[team] {
+		if team {
+
+			if end_pos == (Location{x: 6, y: 0}) {
+				if c.wK {
+					if c.isSquareAttacked(!team, start_pos) {
+						return false, PossibleMove{invalid: true}
+					}
+					sq1 := Location{x: 5, y: 0}
+					if c, _ := c.hasPieceInPosition(sq1.toByte(), false); c {
+						return false, PossibleMove{invalid: true}
+					}
+					if c.isSquareAttacked(!team, sq1) {
+						return false, PossibleMove{invalid: true}
+					}
+
+					sq2 := Location{x: 6, y: 0}
+					if c, _ := c.hasPieceInPosition(sq2.toByte(), false); c {
+						return false, PossibleMove{invalid: true}
+					}
+					if c.isSquareAttacked(!team, sq2) {
+						return false, PossibleMove{invalid: true}
+					}
+
+					c.white[4] = Piece(end_pos.toByte())
+					c.white[7] = Piece(sq1.toByte())
+					cep = sq1
+					castling = true
+					c.wK = false
+					c.wQ = false
+
+				} else {
+					return false, PossibleMove{invalid: true}
+				}
+
+			}
+
+			if end_pos == (Location{x: 2, y: 0}) {
+				if c.wQ {
+					if c.isSquareAttacked(!team, start_pos) {
+						return false, PossibleMove{invalid: true}
+					}
+					sq1 := Location{x: 1, y: 0}
+					if c, _ := c.hasPieceInPosition(sq1.toByte(), false); c {
+						return false, PossibleMove{invalid: true}
+					}
+
+					sq2 := Location{x: 2, y: 0}
+					if c, _ := c.hasPieceInPosition(sq2.toByte(), false); c {
+						return false, PossibleMove{invalid: true}
+					}
+					if c.isSquareAttacked(!team, sq2) {
+						return false, PossibleMove{invalid: true}
+					}
+
+					sq3 := Location{x: 3, y: 0}
+					if c, _ := c.hasPieceInPosition(sq3.toByte(), false); c {
+						return false, PossibleMove{invalid: true}
+					}
+					if c.isSquareAttacked(!team, sq3) {
+						return false, PossibleMove{invalid: true}
+					}
+
+					c.white[4] = Piece(end_pos.toByte())
+					c.white[0] = Piece(sq3.toByte())
+					cep = sq3
+					castling = true
+					c.wK = false
+					c.wQ = false
+					cq = true
+
+				} else {
+					return false, PossibleMove{invalid: true}
+				}
+			}
+
+		} else {
+
+			if end_pos == (Location{x: 6, y: 7}) {
+				if c.bK {
+					if c.isSquareAttacked(!team, start_pos) {
+						return false, PossibleMove{invalid: true}
+					}
+					sq1 := Location{x: 5, y: 7}
+					if c, _ := c.hasPieceInPosition(sq1.toByte(), false); c {
+						return false, PossibleMove{invalid: true}
+					}
+					if c.isSquareAttacked(!team, sq1) {
+						return false, PossibleMove{invalid: true}
+					}
+
+					sq2 := Location{x: 6, y: 7}
+					if c, _ := c.hasPieceInPosition(sq2.toByte(), false); c {
+						return false, PossibleMove{invalid: true}
+					}
+					if c.isSquareAttacked(!team, sq2) {
+						return false, PossibleMove{invalid: true}
+					}
+
+					c.black[4] = Piece(end_pos.toByte())
+					c.black[7] = Piece(sq1.toByte())
+					cep = sq1
+					castling = true
+					c.bK = false
+					c.bQ = false
+				}
+			}
+
+			if end_pos == (Location{x: 2, y: 7}) {
+				if c.bQ {
+					if c.isSquareAttacked(!team, start_pos) {
+						return false, PossibleMove{invalid: true}
+					}
+					sq1 := Location{x: 1, y: 7}
+					if c, _ := c.hasPieceInPosition(sq1.toByte(), false); c {
+						return false, PossibleMove{invalid: true}
+					}
+
+					sq2 := Location{x: 2, y: 7}
+					if c, _ := c.hasPieceInPosition(sq2.toByte(), false); c {
+						return false, PossibleMove{invalid: true}
+					}
+					if c.isSquareAttacked(!team, sq2) {
+						return false, PossibleMove{invalid: true}
+					}
+
+					sq3 := Location{x: 3, y: 7}
+					if c, _ := c.hasPieceInPosition(sq3.toByte(), false); c {
+						return false, PossibleMove{invalid: true}
+					}
+					if c.isSquareAttacked(!team, sq3) {
+						return false, PossibleMove{invalid: true}
+					}
+
+					c.black[4] = Piece(end_pos.toByte())
+					c.black[0] = Piece(sq3.toByte())
+					cep = sq3
+					castling = true
+					c.bK = false
+					c.bQ = false
+					cq = true
+				}
+			}
+		}
+	}
+	if castling {
+		c.toMove = !c.toMove
+		tower := 7
+		if cq {
+			tower = 0
+		}
+		predicted_score += 3
+		return true, PossibleMove{castle: true, score: predicted_score, piece: int(piece), end_pos: end_pos, spiece: tower, send_pos: cep, wK: c.wK, wQ: c.wQ, bK: c.bK, bQ: c.bQ}
+	}
+
+	// A king can only move two squares by castling
+	if piece == 4 && (end_pos.x-start_pos.x > 1 || start_pos.x-end_pos.x > 1) {
+		return false, PossibleMove{invalid: true}
+	}
+
+	// Check if piece can move there
+	ep := end_pos.toByte()
+	for moveLines := 0; moveLines < 8; moveLines++ {
+		for move := 0; move < 7; move++ {
+			value := moveset.Mset[piecei+56*start_pos.y+56*8*start_pos.x+moveLines*7+move]
+
+			if value == 0 {
+				break
+			}
+
+			// if position is valid
+			if (ep & 0b111111) == (value & 0b111111) {
+
+				// Special Pawn rules
+				if piece > 7 && piece < 16 {
+
+					if moveLines == 0 {
+
+						// check if doing En passant
+						if move == 1 {
+							l := Location{}
+							l.fromByte(moveset.Mset[piecei+56*start_pos.y+56*8*start_pos.x+moveLines*7])
+							c.enpassant = l.toByte()
+							enpassant_active = true
+						}
+						canAttack = false
+						valid = true
+					} else {
+
+						// Take en passant into account
+						if co, _ := c.hasPieceInPosition((value&0b111111)|1<<7, true); co {
+							valid = true
+							if c.enpassant != 0 {
+								enpassant = true
+							}
+
+						}
+					}
+
+					if end_pos.y == 7 && team {
+						promotion = true
+
+					}
+
+					if end_pos.y == 0 && !team {
+						promotion = true
+
+					}
+				} else {
+					valid = true
+				}
+
+			} else {
+				if c, _ := c.hasPieceInPosition((value&0b111111)|1<<7, false); c {
+					break
+				}
+			}
+
+		}
+		if valid {
+			break
+		}
+	}
+
+	if !valid {
+		return false, PossibleMove{invalid: true}
+	}
+
+	// Check if piece can capture target (if there is a target)
+	target, target_p := c.hasPieceInPosition(ep, enpassant)
+	if target {
+		pieceTeam := target_p >> 5
+		// Cannot capture your own pieces
+		if pieceTeam == 1 && team {
+			return false, PossibleMove{invalid: true}
+		}
+		if pieceTeam == 0 && !team {
+			return false, PossibleMove{invalid: true}
+		}
+		if !canAttack {
+			return false, PossibleMove{invalid: true}
+		}
+
+	}
+
+	// Make the Move and check if king is in check
+	if team {
+		c.white[piece] = Piece(ep)
+	} else {
+		c.black[piece] = Piece(ep)
+	}
+	oldbQ := c.bQ
+	oldbK := c.bK
+
+	oldwQ := c.wQ
+	oldwK := c.wK
+
+	// Capture piece
+	if target {
+		predicted_score += 5
+		if piece >= 8 && piece < 16 {
+			predicted_score += 5
+		}
+		if team {
+			if target_p&0x1F == 0 {
+				c.bQ = false
+			}
+			if target_p&0x1F == 7 {
+				c.bK = false
+			}
+			c.black[target_p&0x1F] = 0
+		} else {
+			if target_p&0x1F == 0 {
+				c.wQ = false
+			}
+			if target_p&0x1F == 7 {
+				c.wK = false
+			}
+			c.white[target_p&0x1F] = 0
+		}
+	}
+
+	if !enpassant_active {
+		c.enpassant = 0
+	}
+
+	if promotion {
+		predicted_score += 10
+		if team {
+			for i := 16; i < 24; i++ {
+				if c.white[i] == 0 {
+					c.whitePieceMap[i] = string(promote_to)
+					c.white[i] = c.white[piece]
+					c.white[piece] = 0
+					promotion_idx = i
+					break
+				}
+			}
+		} else {
+			for i := 16; i < 24; i++ {
+				if c.black[i] == 0 {
+					c.blackPieceMap[i] = string(promote_to)
+					c.black[i] = c.black[piece]
+					c.black[piece] = 0
+					promotion_idx = i
+					break
+				}
+			}
+		}
+
+	}
+
+	// Check if move was valid by check rules and rollback if invalid
+	if !c.VerifyState(team) {
+		if team {
+			c.white[piece] = Piece(start_pos.toByte())
+		} else {
+			c.black[piece] = Piece(start_pos.toByte())
+		}
+		if target {
+			if team {
+				if target_p&0xF == 0 {
+					c.bQ = oldbQ
+				}
+				if target_p&0xF == 7 {
+					c.bK = oldbK
+				}
+				c.black[target_p&0xF] = Piece(ep)
+			} else {
+				if target_p&0xF == 0 {
+					c.wQ = oldwQ
+				}
+				if target_p&0xF == 7 {
+					c.wK = oldwK
+				}
+				c.white[target_p&0xF] = Piece(ep)
+			}
+		}
+
+		if promotion {
+			if team {
+				c.white[promotion_idx] = 0
+			} else {
+				c.black[promotion_idx] = 0
+			}
+
+		}
+		return false, PossibleMove{invalid: true}
+	}
+
+	// Remove castling rights
+	if piece == 4 {
+		predicted_score -= 3
+		if team {
+			c.wK = false
+			c.wQ = false
+		} else {
+			c.bK = false
+			c.bQ = false
+		}
+	}
+
+	// Moving a rook removes the castling rights on its side
+	if piece == 0 {
+		if team {
+			c.wQ = false
+		} else {
+			c.bQ = false
+		}
+	}
+	if piece == 7 {
+		if team {
+			c.wK = false
+		} else {
+			c.bK = false
+		}
+	}
+
+	c.toMove = !c.toMove
+
+	truetargetp := 0
+	if target {
+		truetargetp = target_p | 1<<5
+	}
+
+	return true, PossibleMove{score: predicted_score, piece: int(piece), end_pos: end_pos,
+		wK: c.wK, wQ: c.wQ, bK: c.bK, bQ: c.bQ,
+		enpassant: c.enpassant,
+		target:    uint8(truetargetp),
+		promote:   promotion, promote_to: promote_to}
+}
+
+/*
+	Makes a move from a PossibleMove (no validity checks are made)
+*/
+func (c *Chessboard) MakeUnsafeMove(pm PossibleMove, team bool) {
+
+	// Update the hash: side to move, castling rights, en passant and the pieces that move
+	h := c.hash ^ zobrist.zh_black_to_move ^ zobrist.castlingKey(c.wK, c.wQ, c.bK, c.bQ) ^ zobrist.enpassantKey(c.hash_ep)
+	pieces, opponent := &c.black, &c.white
+	if team {
+		pieces, opponent = &c.white, &c.black
+	}
+	name := c.PieceName(pm.piece, team)
+	h ^= zobrist.pieceKey(name, team, pieces[pm.piece])
+	if pm.promote {
+		h ^= zobrist.pieceKey(string(pm.promote_to), team, Piece(pm.end_pos.toByte()))
+	} else {
+		h ^= zobrist.pieceKey(name, team, Piece(pm.end_pos.toByte()))
+	}
+	if pm.castle {
+		h ^= zobrist.pieceKey("r", team, pieces[pm.spiece])
+		h ^= zobrist.pieceKey("r", team, Piece(pm.send_pos.toByte()))
+	}
+	if pm.target != 0 {
+		target := int(pm.target & 0x1F)
+		h ^= zobrist.pieceKey(c.PieceName(target, !team), !team, opponent[target])
+	}
+
+	// Make the Move and check if king is in check
+	if team {
+		c.white[pm.piece] = Piece(pm.end_pos.toByte())
+		if pm.castle {
+			c.white[pm.spiece] = Piece(pm.send_pos.toByte())
+		}
+	} else {
+		c.black[pm.piece] = Piece(pm.end_pos.toByte())
+		if pm.castle {
+			c.black[pm.spiece] = Piece(pm.send_pos.toByte())
+		}
+	}
+	c.bQ = pm.bQ
+	c.bK = pm.bK
+
+	c.wQ = pm.wQ
+	c.wK = pm.wK
+
+	// Capture piece
+	if pm.target != 0 {
+		if team {
+			c.black[pm.target&0x1F] = 0
+		} else {
+			c.white[pm.target&0x1F] = 0
+		}
+	}
+
+	c.enpassant = pm.enpassant
+
+	c.advanceClocks(team, pm.target != 0 || (pm.piece > 7 && pm.piece < 16))
+
+	if pm.promote {
+		if team {
+			for i := 16; i < 24; i++ {
+				if c.white[i] == 0 {
+					c.whitePieceMap[i] = string(pm.promote_to)
+					c.white[i] = c.white[pm.piece]
+					c.white[pm.piece] = 0
+					break
+				}
+			}
+		} else {
+			for i := 16; i < 24; i++ {
+				if c.black[i] == 0 {
+					c.blackPieceMap[i] = string(pm.promote_to)
+					c.black[i] = c.black[pm.piece]
+					c.black[pm.piece] = 0
+					break
+				}
+			}
+		}
+
+	}
+
+	c.toMove = !team
+	c.hash_ep = c.enpassantCapture(c.toMove)
+	c.hash = h ^ zobrist.castlingKey(c.wK, c.wQ, c.bK, c.bQ) ^ zobrist.enpassantKey(c.hash_ep)
+	c.recordPosition()
+}
+
+
+/*
+	Passes the turn without moving, for the null-move pruning of the search. The
+	halfmove clock restarts so that no repetition is counted across the null move
+*/
+func (c *Chessboard) makeNullMove() {
+	c.hash ^= zobrist.zh_black_to_move ^ zobrist.enpassantKey(c.hash_ep)
+	c.toMove = !c.toMove
+	c.enpassant = 0
+	c.hash_ep = 0
+	c.mc = 0
+	c.recordPosition()
+}
+
+/*
+	Advances the halfmove clock and the fullmove number after a move of TEAM.
+	RESET is TRUE for pawn moves and captures
+*/
+func (c *Chessboard) advanceClocks(team bool, reset bool) {
+	if reset {
+		c.mc = 0
+	} else {
+		c.mc++
+	}
+	if !team {
+		c.rounds++
+	}
+}
+
+// Calculate all possible moves for a team
+func (c *Chessboard) PossibleMoves(team bool) []PossibleMove {
+	return c.generateMoves(team, false)
+}
+
+/*
+	Returns the captures and promotions of TEAM. Cheaper than PossibleMoves as
+	only the moves to squares holding an opponent piece (or the last rank for
+	pawns) are tested
+*/
+func (c *Chessboard) PossibleCaptures(team bool) []PossibleMove {
+	return c.generateMoves(team, true)
+}
+
+// Legal moves of TEAM, only its captures, en passant and promotions if CAPTURES is TRUE
+func (c *Chessboard) generateMoves(team bool, captures bool) []PossibleMove {
+	moves := make([]PossibleMove, 0)
+
+	pieces := &c.black
+	if team {
+		pieces = &c.white
+	}
+
+	var board Chessboard
+	for idx, pos := range pieces {
+		if pos == 0 {
+			continue
+		}
+
+		// Get piece memory offset, black pawns and king have their own tables
+		piecei := 0
+		if idx > 15 {
+			piecei = pieceMap[c.PieceName(idx, team)[0]]
+		} else {
+			piecei = pieceMap[indexPieceMap[idx][0]]
+		}
+		pawn := idx > 7 && idx < 16
+		if !team && pawn {
+			piecei = pieceMap['P']
+		}
+		if !team && idx == 4 {
+			piecei = pieceMap['K']
+		}
+
+		start_pos := Location{}
+		start_pos.fromByte(uint8(pos))
+
+		// Check all moves
+		for moveLines := 0; moveLines < 8; moveLines++ {
+			for move := 0; move < 7; move++ {
+				value := moveset.Mset[piecei+56*start_pos.y+56*8*start_pos.x+moveLines*7+move]
+
+				if value == 0 {
+					break
+				}
+
+				end_pos := Location{}
+				end_pos.fromByte(value)
+
+				if captures {
+					found, target := c.hasPieceInPosition(end_pos.toByte(), false)
+					capture := found && (target>>5 == 1) != team
+					promotion := pawn && (team && end_pos.y == 7 || !team && end_pos.y == 0)
+					if !capture && !promotion && !(pawn && end_pos.toByte() == c.enpassant) {
+						continue
+					}
+				}
+
+				c.SaveState(&board)
+
+				var tg PossibleMove
+				var co bool
+				if co, tg = board.TestMove(uint8(idx), team, end_pos, 'q'); !co {
+					continue
+				}
+
+				if tg.invalid {
+					log.Panicf("tg invalid at %d %t %s", idx, team, end_pos.PGN())
+				}
+
+				moves = append(moves, tg)
+
+				// Every underpromotion is a move of its own
+				if tg.promote {
+					for _, promote_to := range []byte{'n', 'r', 'b'} {
+						under := tg
+						under.promote_to = promote_to
+						moves = append(moves, under)
+					}
+				}
+			}
+		}
+	}
+	return moves
+}
+
+func (c *Chessboard) calculateAllMovements(depth int, layer bool) int {
+	pm1 := c.PossibleMoves(layer)
+
+	var Board Chessboard
+	if depth > 1 {
+		total := 0
+		for _, p := range pm1 {
+
+			c.SaveState(&Board)
+			Board.MakeUnsafeMove(p, layer)
+			total += Board.calculateAllMovements(depth-1, !layer)
+		}
+		return total
+	} else {
+		/*for _, p := range pm1 {
+			c.SaveState(&Board)
+			Board.MakeUnsafeMove(p, layer)
+			log.Printf(Board.Fen())
+		}*/
+		total := len(pm1)
+		return total
+	}
+
+}
@@ -0,0 +1,259 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Checks if POS is a valid square in PGN (e4)
+func validSquare(pos string) bool {
+	return len(pos) == 2 && pos[0] >= 'a' && pos[0] <= 'h' && pos[1] >= '1' && pos[1] <= '8'
+}
+
+// Converts a possible move of the team to move into UCI notation (e2e4, e7e8q)
+func (c *Chessboard) MoveUCI(pm PossibleMove) string {
+	start_pos := c.PieceLocation(pm.piece, c.toMove)
+	if pm.promote {
+		return fmt.Sprintf("%s%s%c", start_pos.PGN(), pm.end_pos.PGN(), pm.promote_to)
+	}
+	return start_pos.PGN() + pm.end_pos.PGN()
+}
+
+// Makes a move written in UCI notation for the team to move and returns TRUE if it was valid
+func (c *Chessboard) MakeUCIMove(move string) bool {
+	if len(move) < 4 || len(move) > 5 || !validSquare(move[0:2]) || !validSquare(move[2:4]) {
+		return false
+	}
+
+	start_pos := Location{}
+	start_pos.FromPGN(move[0:2])
+	end_pos := Location{}
+	end_pos.FromPGN(move[2:4])
+
+	promote_to := byte('q')
+	if len(move) == 5 {
+		promote_to = move[4]
+		if strings.IndexByte("qrbn", promote_to) == -1 {
+			return false
+		}
+	}
+
+	// The piece must belong to the team to move
+	found, piece := c.hasPieceInPosition(start_pos.toByte(), false)
+	if !found || (piece>>5 == 1) != c.toMove {
+		return false
+	}
+
+	valid, _ := c.MakeMove(uint8(piece&0x1F), c.toMove, end_pos, promote_to)
+	return valid
+}
+
+// Converts a LINE of moves, starting with the team to move, into UCI notation. The moves are not made
+func (c *Chessboard) LineUCI(line []PossibleMove) []string {
+	board := c.Duplicate()
+	moves := []string{}
+	for _, pm := range line {
+		moves = append(moves, board.MoveUCI(pm))
+		board.MakeUnsafeMove(pm, board.toMove)
+	}
+	return moves
+}
+
+// Piece letters used by Standard Algebraic Notation
+const sanPieces = "NBRQK"
+
+/*
+	Converts a possible move of the team to move into Standard Algebraic Notation
+	(e4, Nbd7, exd5, O-O, e8=Q+). The move is not made
+*/
+func (c *Chessboard) ToSAN(pm PossibleMove) string {
+	if pm.invalid {
+		return "--"
+	}
+	team := c.toMove
+
+	san := ""
+	if pm.castle {
+		san = "O-O"
+		if pm.end_pos.x == 2 {
+			san = "O-O-O"
+		}
+	} else {
+		name := c.PieceName(pm.piece, team)
+		start_pos := c.PieceLocation(pm.piece, team)
+
+		if name == "p" {
+			if pm.target != 0 {
+				san = start_pos.PGN()[:1] + "x"
+			}
+			san += pm.end_pos.PGN()
+			if pm.promote {
+				san += "=" + strings.ToUpper(string(pm.promote_to))
+			}
+		} else {
+			san = strings.ToUpper(name) + c.sanDisambiguation(pm, name, start_pos)
+			if pm.target != 0 {
+				san += "x"
+			}
+			san += pm.end_pos.PGN()
+		}
+	}
+
+	// Check and checkmate suffixes
+	var board Chessboard
+	c.SaveState(&board)
+	board.MakeUnsafeMove(pm, team)
+	if !board.VerifyState(!team) {
+		if len(board.PossibleMoves(!team)) == 0 {
+			return san + "#"
+		}
+		return san + "+"
+	}
+	return san
+}
+
+/*
+	Returns the file, rank or square needed to tell PM apart from the moves of
+	other pieces of type NAME that reach the same square
+*/
+func (c *Chessboard) sanDisambiguation(pm PossibleMove, name string, start_pos Location) string {
+	same_file := false
+	same_rank := false
+	ambiguous := false
+	for _, other := range c.PossibleMoves(c.toMove) {
+		if other.piece == pm.piece || other.end_pos != pm.end_pos || c.PieceName(other.piece, c.toMove) != name {
+			continue
+		}
+		ambiguous = true
+		other_pos := c.PieceLocation(other.piece, c.toMove)
+		if other_pos.x == start_pos.x {
+			same_file = true
+		}
+		if other_pos.y == start_pos.y {
+			same_rank = true
+		}
+	}
+
+	pos := start_pos.PGN()
+	switch {
+	case !ambiguous:
+		return ""
+	case !same_file:
+		return pos[:1]
+	case !same_rank:
+		return pos[1:]
+	default:
+		return pos
+	}
+}
+
+/*
+	Finds the legal move of the team to move written in Standard Algebraic Notation.
+	Check, mate and annotation suffixes are optional
+*/
+func (c *Chessboard) ParseSAN(move string) (PossibleMove, error) {
+	san := strings.TrimRight(strings.TrimSpace(move), "+#!?")
+	team := c.toMove
+
+	// Castling
+	castle_file := -1
+	switch san {
+	case "O-O", "0-0":
+		castle_file = 6
+	case "O-O-O", "0-0-0":
+		castle_file = 2
+	}
+	if castle_file != -1 {
+		for _, pm := range c.PossibleMoves(team) {
+			if pm.castle && pm.end_pos.x == castle_file {
+				return pm, nil
+			}
+		}
+		return PossibleMove{invalid: true}, fmt.Errorf("illegal move %q", move)
+	}
+
+	// Moving piece, pawns have no letter
+	name := "p"
+	if len(san) > 0 && strings.IndexByte(sanPieces, san[0]) != -1 {
+		name = strings.ToLower(san[:1])
+		san = san[1:]
+	}
+
+	// Promotion, with or without the '='
+	promote_to := byte(0)
+	if name == "p" && len(san) > 2 && strings.IndexByte(sanPieces[:4], san[len(san)-1]) != -1 {
+		promote_to = strings.ToLower(san[len(san)-1:])[0]
+		san = strings.TrimSuffix(san[:len(san)-1], "=")
+	}
+
+	// What remains is [file][rank][x]square
+	san = strings.Replace(san, "x", "", 1)
+	if len(san) < 2 || len(san) > 4 || !validSquare(san[len(san)-2:]) {
+		return PossibleMove{invalid: true}, fmt.Errorf("invalid move %q", move)
+	}
+	end_pos := Location{}
+	end_pos.FromPGN(san[len(san)-2:])
+	from := san[:len(san)-2]
+
+	candidates := []PossibleMove{}
+	for _, pm := range c.PossibleMoves(team) {
+		if pm.castle || pm.end_pos != end_pos || c.PieceName(pm.piece, team) != name {
+			continue
+		}
+		if pm.promote {
+			// A missing promotion piece means a queen
+			want := promote_to
+			if want == 0 {
+				want = 'q'
+			}
+			if pm.promote_to != want {
+				continue
+			}
+		} else if promote_to != 0 {
+			continue
+		}
+
+		start_pos := c.PieceLocation(pm.piece, team).PGN()
+		matches := true
+		for _, char := range from {
+			if !strings.ContainsRune(start_pos, char) {
+				matches = false
+			}
+		}
+		if matches {
+			candidates = append(candidates, pm)
+		}
+	}
+
+	switch len(candidates) {
+	case 0:
+		return PossibleMove{invalid: true}, fmt.Errorf("illegal move %q", move)
+	case 1:
+		return candidates[0], nil
+	default:
+		return PossibleMove{invalid: true}, fmt.Errorf("ambiguous move %q", move)
+	}
+}
+
+// Converts a LINE of moves, starting with the team to move, into Standard Algebraic Notation. The moves are not made
+func (c *Chessboard) LineSAN(line []PossibleMove) []string {
+	board := c.Duplicate()
+	moves := []string{}
+	for _, pm := range line {
+		moves = append(moves, board.ToSAN(pm))
+		board.MakeUnsafeMove(pm, board.toMove)
+	}
+	return moves
+}
+
+// Makes a move written in Standard Algebraic Notation for the team to move
+func (c *Chessboard) MakeSANMove(move string) error {
+	pm, err := c.ParseSAN(move)
+	if err != nil {
+		return err
+	}
+	if valid, _ := c.MakeMove(uint8(pm.piece), c.toMove, pm.end_pos, pm.promote_to); !valid {
+		return fmt.Errorf("illegal move %q", move)
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package engine
+
+import "testing"
+
+// Finds the legal move written in UCI notation
+func moveByUCI(t *testing.T, c *Chessboard, uci string) PossibleMove {
+	t.Helper()
+	for _, pm := range c.PossibleMoves(c.toMove) {
+		if c.MoveUCI(pm) == uci {
+			return pm
+		}
+	}
+	t.Fatalf("%s is not a legal move in %s", uci, c.Fen())
+	return PossibleMove{}
+}
+
+var sanCases = []struct {
+	fen string
+	uci string
+	san string
+}{
+	{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "e2e4", "e4"},
+	{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "g1f3", "Nf3"},
+	{"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1", "e1g1", "O-O"},
+	{"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1", "e1c1", "O-O-O"},
+	{"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1", "e5f7", "Nxf7"},
+	{"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1", "d5e6", "dxe6"},
+	{"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R b KQkq - 0 1", "e8c8", "O-O-O"},
+	{"4k3/8/8/8/8/8/8/R4RK1 w - - 0 1", "a1e1", "Rae1+"},
+	{"4k3/8/8/8/8/8/8/R4RK1 w - - 0 1", "f1e1", "Rfe1+"},
+	{"4k3/8/8/R7/8/8/8/R3K3 w - - 0 1", "a5a3", "R5a3"},
+	{"4k3/8/8/R7/8/8/8/R3K3 w - - 0 1", "a1a3", "R1a3"},
+	{"4k3/8/8/8/8/Q7/8/Q1Q1K3 w - - 0 1", "a1b2", "Qa1b2"},
+	{"4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 1", "e5d6", "exd6"},
+	{"4k3/8/8/8/8/8/8/R3K3 w Q - 0 1", "a1a8", "Ra8+"},
+	{"6k1/5ppp/8/8/8/8/8/R3K3 w - - 0 1", "a1a8", "Ra8#"},
+	{"7k/P7/8/8/8/8/8/K7 w - - 0 1", "a7a8q", "a8=Q+"},
+	{"7k/P7/8/8/8/8/8/K7 w - - 0 1", "a7a8r", "a8=R+"},
+	{"7k/P7/8/8/8/8/8/K7 w - - 0 1", "a7a8b", "a8=B"},
+	{"7k/P7/8/8/8/8/8/K7 w - - 0 1", "a7a8n", "a8=N"},
+	{"1n5k/P7/8/8/8/8/8/K7 w - - 0 1", "a7b8n", "axb8=N"},
+}
+
+func TestToSAN(t *testing.T) {
+	for _, tc := range sanCases {
+		board := Chessboard{}
+		board.FromFen(tc.fen)
+		if san := board.ToSAN(moveByUCI(t, &board, tc.uci)); san != tc.san {
+			t.Errorf("%s in %s: ToSAN = %s, want %s", tc.uci, tc.fen, san, tc.san)
+		}
+	}
+}
+
+func TestParseSAN(t *testing.T) {
+	for _, tc := range sanCases {
+		board := Chessboard{}
+		board.FromFen(tc.fen)
+		pm, err := board.ParseSAN(tc.san)
+		if err != nil {
+			t.Errorf("%s in %s: %v", tc.san, tc.fen, err)
+			continue
+		}
+		if uci := board.MoveUCI(pm); uci != tc.uci {
+			t.Errorf("%s in %s: ParseSAN = %s, want %s", tc.san, tc.fen, uci, tc.uci)
+		}
+	}
+}
+
+func TestParseSANVariants(t *testing.T) {
+	board := Chessboard{}
+	board.FromFen("7k/P7/8/8/8/8/8/K7 w - - 0 1")
+	for _, san := range []string{"a8Q", "a8=Q", "a8", "a8=Q+!"} {
+		pm, err := board.ParseSAN(san)
+		if err != nil || board.MoveUCI(pm) != "a7a8q" {
+			t.Errorf("ParseSAN(%s) = %s, %v, want a7a8q", san, board.MoveUCI(pm), err)
+		}
+	}
+
+	board.FromFen("r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+	if pm, err := board.ParseSAN("0-0"); err != nil || board.MoveUCI(pm) != "e1g1" {
+		t.Errorf("ParseSAN(0-0) = %s, %v, want e1g1", board.MoveUCI(pm), err)
+	}
+	if pm, err := board.ParseSAN("Ne5xf7"); err != nil || board.MoveUCI(pm) != "e5f7" {
+		t.Errorf("ParseSAN(Ne5xf7) = %s, %v, want e5f7", board.MoveUCI(pm), err)
+	}
+}
+
+func TestParseSANErrors(t *testing.T) {
+	for _, tc := range []struct{ fen, san string }{
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "Nf6"},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "O-O"},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "e9"},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", ""},
+		{"4k3/8/8/8/8/8/8/R4RK1 w - - 0 1", "Re1"},
+	} {
+		board := Chessboard{}
+		board.FromFen(tc.fen)
+		if pm, err := board.ParseSAN(tc.san); err == nil {
+			t.Errorf("ParseSAN(%q) = %s, want an error", tc.san, board.MoveUCI(pm))
+		}
+	}
+}
+
+// Every legal move must survive a round trip through SAN
+func TestSANRoundTrip(t *testing.T) {
+	for _, tc := range perftCases {
+		board := Chessboard{}
+		board.FromFen(tc.fen)
+		for _, pm := range board.PossibleMoves(board.toMove) {
+			san := board.ToSAN(pm)
+			parsed, err := board.ParseSAN(san)
+			if err != nil {
+				t.Errorf("%s: %s (%s): %v", tc.name, san, board.MoveUCI(pm), err)
+				continue
+			}
+			if board.MoveUCI(parsed) != board.MoveUCI(pm) {
+				t.Errorf("%s: %s parsed as %s, want %s", tc.name, san, board.MoveUCI(parsed), board.MoveUCI(pm))
+			}
+		}
+	}
+}
+
+func TestMakeSANMove(t *testing.T) {
+	board := Chessboard{}
+	board.FromFen("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1")
+	for _, san := range []string{"e4", "e5", "Nf3", "Nc6", "Bb5", "a6", "O-O"} {
+		if err := board.MakeSANMove(san); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := Chessboard{}
+	want.FromFen("r1bqkbnr/1ppp1ppp/p1n5/1B2p3/4P3/5N2/PPPP1PPP/RNBQ1RK1 b kq - 1 4")
+	if board.Fen() != want.Fen() {
+		t.Errorf("Fen = %s, want %s", board.Fen(), want.Fen())
+	}
+}
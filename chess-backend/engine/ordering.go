@@ -0,0 +1,143 @@
+package engine
+
+import "sort"
+
+// Move ordering scores, higher is searched first
+const (
+	ORDER_HASH_MOVE = 1 << 30
+	ORDER_CAPTURE   = 1 << 24
+	ORDER_KILLER    = 1 << 20
+)
+
+// Killer moves kept per ply
+const KILLER_MOVES = 2
+
+// Plies with killer moves, deeper plies go without
+const MAX_PLY = MAX_SEARCH_DEPTH + 16
+
+// Quiet move that caused a cutoff
+type killerMove struct {
+	piece   int
+	end_pos uint8
+}
+
+// Cutoff counters of the last search
+type SearchStats struct {
+	// Nodes where a move failed high
+	Cutoffs int
+	// Cutoffs caused by the first move searched
+	FirstMoveCutoffs int
+	// Moves searched deeper by an extension, and the ones of them that were singular
+	Extensions         int
+	SingularExtensions int
+}
+
+// Percentage of cutoffs on the first move, the closer to 100 the better the ordering
+func (s SearchStats) FirstMoveRate() float64 {
+	if s.Cutoffs == 0 {
+		return 0
+	}
+	return 100 * float64(s.FirstMoveCutoffs) / float64(s.Cutoffs)
+}
+
+// Adds the counters of OTHER
+func (s *SearchStats) add(other SearchStats) {
+	s.Cutoffs += other.Cutoffs
+	s.FirstMoveCutoffs += other.FirstMoveCutoffs
+	s.Extensions += other.Extensions
+	s.SingularExtensions += other.SingularExtensions
+}
+
+// Move ordering state, kept across the iterations of a search
+type moveOrdering struct {
+	killers [MAX_PLY][KILLER_MOVES]killerMove
+	// Depth squared of the cutoffs of each piece to each square, per team
+	history [2][24][64]int
+}
+
+// Index of TEAM in the ordering tables
+func teamIndex(team bool) int {
+	if team {
+		return 1
+	}
+	return 0
+}
+
+// Starts a new search: killers are forgotten and the history fades
+func (o *moveOrdering) newSearch() {
+	o.killers = [MAX_PLY][KILLER_MOVES]killerMove{}
+	for t := range o.history {
+		for p := range o.history[t] {
+			for sq := range o.history[t][p] {
+				o.history[t][p][sq] /= 8
+			}
+		}
+	}
+}
+
+// Returns TRUE if PM is a capture or a promotion
+func (pm *PossibleMove) tactical() bool {
+	return pm.target != 0 || pm.promote
+}
+
+/*
+	Sorts the moves PM of TEAM at PLY: the hash move first, then captures by
+	most valuable victim and least valuable attacker, the killer moves of the
+	ply and the quiet moves by their history
+*/
+func (e *Engine) orderMoves(c *Chessboard, pm []PossibleMove, team bool, ply int, hash TranspositionEntry) {
+	t := teamIndex(team)
+	for i := range pm {
+		state := &pm[i]
+		to := state.end_pos.toByte() & 0b111111
+		if hash.isMove(*state) {
+			state.score = ORDER_HASH_MOVE
+		} else if state.tactical() {
+			state.score = ORDER_CAPTURE + c.materialGain(*state, team)*10 - c.pieceValue(state.piece, team)/100
+		} else {
+			state.score = float64(e.ordering.history[t][state.piece][to])
+			if ply < MAX_PLY {
+				for k, killer := range e.ordering.killers[ply] {
+					if killer.piece == state.piece && killer.end_pos == to {
+						state.score = ORDER_KILLER - float64(k)
+						break
+					}
+				}
+			}
+		}
+	}
+	sort.SliceStable(pm, func(i, j int) bool {
+		return pm[i].score > pm[j].score
+	})
+}
+
+// Records the cutoff of the move PM of TEAM at PLY, the INDEX-th move searched in a search to DEPTH
+func (e *Engine) recordCutoff(pm PossibleMove, team bool, ply int, depth int, index int) {
+	e.stats.Cutoffs++
+	if index == 0 {
+		e.stats.FirstMoveCutoffs++
+	}
+	if pm.tactical() {
+		return
+	}
+
+	to := pm.end_pos.toByte() & 0b111111
+	if ply < MAX_PLY {
+		killers := &e.ordering.killers[ply]
+		if killers[0].piece != pm.piece || killers[0].end_pos != to {
+			killers[1] = killers[0]
+			killers[0] = killerMove{piece: pm.piece, end_pos: to}
+		}
+	}
+
+	// Keep the history under the killer moves
+	history := &e.ordering.history[teamIndex(team)]
+	history[pm.piece][to] += depth * depth
+	if history[pm.piece][to] >= ORDER_KILLER {
+		for p := range history {
+			for sq := range history[p] {
+				history[p][sq] /= 2
+			}
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package engine
+
+import (
+	"math"
+	"testing"
+)
+
+// Finds the move written MOVE in PM
+func findMove(t *testing.T, c *Chessboard, pm []PossibleMove, move string) PossibleMove {
+	for _, state := range pm {
+		if c.MoveUCI(state) == move {
+			return state
+		}
+	}
+	t.Fatalf("move %s not found", move)
+	return PossibleMove{}
+}
+
+func TestOrderMoves(t *testing.T) {
+	board := Chessboard{}
+	// The rook on e4 can take the queen on e7 or the pawn on b4, the knight can take the pawn too
+	board.FromFen("4k3/4q3/8/8/1p2R3/3N4/8/6K1 w - - 0 1")
+	pm := board.PossibleMoves(true)
+
+	e := NewEngine()
+	killer := findMove(t, &board, pm, "g1f2")
+	e.recordCutoff(killer, true, 3, 2, 1)
+	history := findMove(t, &board, pm, "d3f4")
+	e.recordCutoff(history, true, 5, 4, 0)
+
+	hash := TranspositionEntry{piece: -1}
+	e.orderMoves(&board, pm, true, 3, hash)
+	want := []string{"e4e7", "d3b4", "e4b4", "g1f2", "d3f4"}
+	for i, move := range want {
+		if got := board.MoveUCI(pm[i]); got != move {
+			t.Errorf("move %d is %s, want %s", i, got, move)
+		}
+	}
+
+	// The hash move goes before everything else
+	hash = TranspositionEntry{piece: int8(killer.piece), end_pos: killer.end_pos.toByte()}
+	e.orderMoves(&board, pm, true, 3, hash)
+	if got := board.MoveUCI(pm[0]); got != "g1f2" {
+		t.Errorf("first move %s, want the hash move g1f2", got)
+	}
+
+	if stats := e.Stats(); stats.Cutoffs != 2 || stats.FirstMoveCutoffs != 1 || stats.FirstMoveRate() != 50 {
+		t.Errorf("stats %+v, want 2 cutoffs, 1 on the first move", stats)
+	}
+}
+
+func TestOrderingKeepsResult(t *testing.T) {
+	board := Chessboard{}
+	board.FromFen("6k1/5ppp/8/8/8/8/5PPP/3R2K1 w - - 0 1")
+
+	e := NewEngine()
+	for depth := 2; depth <= 4; depth++ {
+		states := 0
+		score, pm := e.Minimax(&board, depth, math.Inf(-1), math.Inf(+1), true, &states)
+		if move := board.MoveUCI(pm); move != "d1d8" || score != MATE_SCORE-1 {
+			t.Errorf("depth %d: %s with %.0f, want d1d8 with %d", depth, move, score, MATE_SCORE-1)
+		}
+	}
+}
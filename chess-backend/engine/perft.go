@@ -0,0 +1,29 @@
+package engine
+
+// Counts the leaf nodes of the move tree DEPTH plies deep from the team to move
+func (c *Chessboard) Perft(depth int) int {
+	if depth <= 0 {
+		return 1
+	}
+	return c.calculateAllMovements(depth, c.toMove)
+}
+
+// Counts the leaf nodes below each move of the team to move, keyed by the move in UCI notation
+func (c *Chessboard) Divide(depth int) map[string]int {
+	divide := make(map[string]int)
+	if depth <= 0 {
+		return divide
+	}
+
+	var board Chessboard
+	for _, pm := range c.PossibleMoves(c.toMove) {
+		nodes := 1
+		if depth > 1 {
+			c.SaveState(&board)
+			board.MakeUnsafeMove(pm, c.toMove)
+			nodes = board.calculateAllMovements(depth-1, !c.toMove)
+		}
+		divide[c.MoveUCI(pm)] += nodes
+	}
+	return divide
+}
@@ -0,0 +1,100 @@
+package engine
+
+import "testing"
+
+type perftCase struct {
+	name  string
+	fen   string
+	depth int
+	nodes int
+}
+
+// Standard perft positions (chessprogramming.org) and edge cases for en passant, castling and promotion
+var perftCases = []perftCase{
+	{name: "start position", fen: "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", depth: 4, nodes: 197281},
+	{name: "kiwipete", fen: "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1", depth: 3, nodes: 97862},
+	{name: "position 3", fen: "8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1", depth: 5, nodes: 674624},
+	{name: "position 4", fen: "r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1", depth: 3, nodes: 9467},
+	{name: "position 5", fen: "rnbq1k1r/pp1Pbppp/2p5/8/2B5/8/PPP1NnPP/RNBQK2R w KQ - 1 8", depth: 3, nodes: 62379},
+	{name: "position 6", fen: "r4rk1/1pp1qppp/p1np1n2/2b1p1B1/2B1P1b1/P1NP1N2/1PP1QPPP/R4RK1 w - - 0 10", depth: 3, nodes: 89890},
+
+	{name: "illegal en passant (pinned pawn)", fen: "3k4/3p4/8/K1P4r/8/8/8/8 b - - 0 1", depth: 4, nodes: 10138},
+	{name: "illegal en passant (bishop pin)", fen: "8/8/4k3/8/2p5/8/B2P2K1/8 w - - 0 1", depth: 4, nodes: 10276},
+	{name: "en passant capture checks opponent", fen: "8/8/1k6/2b5/2pP4/8/5K2/8 b - d3 0 1", depth: 4, nodes: 13931},
+	{name: "short castling gives check", fen: "5k2/8/8/8/8/8/8/4K2R w K - 0 1", depth: 4, nodes: 6399},
+	{name: "long castling gives check", fen: "3k4/8/8/8/8/8/8/R3K3 w Q - 0 1", depth: 4, nodes: 7418},
+	{name: "castling rights", fen: "r3k2r/1b4bq/8/8/8/8/7B/R3K2R w KQkq - 0 1", depth: 3, nodes: 27826},
+	{name: "castling prevented", fen: "r3k2r/8/3Q4/8/8/5q2/8/R3K2R b KQkq - 0 1", depth: 3, nodes: 50509},
+	{name: "promote out of check", fen: "2K2r2/4P3/8/8/8/8/8/3k4 w - - 0 1", depth: 4, nodes: 19174},
+	{name: "discovered check", fen: "8/8/1P2K3/8/2n5/1q6/8/5k2 b - - 0 1", depth: 4, nodes: 31961},
+	{name: "promote to give check", fen: "4k3/1P6/8/8/8/8/K7/8 w - - 0 1", depth: 6, nodes: 217342},
+	{name: "under promote to give check", fen: "8/P1k5/K7/8/8/8/8/8 w - - 0 1", depth: 6, nodes: 92683},
+	{name: "self stalemate", fen: "K1k5/8/P7/8/8/8/8/8 w - - 0 1", depth: 6, nodes: 2217},
+	{name: "stalemate and checkmate", fen: "8/k1P5/8/1K6/8/8/8/8 w - - 0 1", depth: 4, nodes: 926},
+	{name: "double check", fen: "8/8/2k5/5q2/5n2/8/5K2/8 b - - 0 1", depth: 4, nodes: 23527},
+}
+
+func TestPerft(t *testing.T) {
+	for _, tc := range perftCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			if testing.Short() && tc.nodes > 100000 {
+				t.Skip("skipping deep perft in short mode")
+			}
+
+			board := Chessboard{}
+			board.FromFen(tc.fen)
+			if nodes := board.Perft(tc.depth); nodes != tc.nodes {
+				t.Errorf("perft(%d) = %d, want %d", tc.depth, nodes, tc.nodes)
+			}
+		})
+	}
+}
+
+func TestDivide(t *testing.T) {
+	board := Chessboard{}
+	board.FromFen("r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+
+	divide := board.Divide(2)
+	if len(divide) != 48 {
+		t.Fatalf("divide has %d root moves, want 48", len(divide))
+	}
+
+	total := 0
+	for _, nodes := range divide {
+		total += nodes
+	}
+	if total != 2039 {
+		t.Errorf("divide total = %d, want 2039", total)
+	}
+
+	// Castling moves are written as king moves
+	for move, want := range map[string]int{"e1g1": 43, "e1c1": 43, "e5f7": 44, "d5e6": 46} {
+		if divide[move] != want {
+			t.Errorf("divide[%s] = %d, want %d", move, divide[move], want)
+		}
+	}
+}
+
+func TestPerftLeavesBoardUntouched(t *testing.T) {
+	fen := "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1"
+	board := Chessboard{}
+	board.FromFen(fen)
+	before := board.Fen()
+
+	board.Perft(2)
+	if after := board.Fen(); after != before {
+		t.Errorf("board changed after perft: %s, want %s", after, before)
+	}
+}
+
+// A king only attacks the squares next to it, not the castling squares of its move table
+func TestKingAttacks(t *testing.T) {
+	for _, fen := range []string{"8/8/8/8/8/8/8/2k1K2R b - - 0 1", "8/8/8/8/8/8/8/R3K1k1 b - - 0 1"} {
+		board := Chessboard{}
+		board.FromFen(fen)
+		if !board.VerifyState(false) {
+			t.Errorf("black king in check in %s", fen)
+		}
+	}
+}
@@ -0,0 +1,428 @@
+package engine
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FEN of the standard start position
+const START_FEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+
+// Longest line of the exported movetext
+const PGN_LINE_LENGTH = 80
+
+// Tags every PGN game starts with, in export order
+var sevenTagRoster = []string{"Event", "Site", "Date", "Round", "White", "Black", "Result"}
+
+// A move of a game in SAN with the comment and Numeric Annotation Glyphs that follow it
+type GameMove struct {
+	SAN     string
+	Comment string
+	NAGs    []int
+}
+
+/*
+	A game record in Portable Game Notation. Tags holds the tag pairs, the Seven
+	Tag Roster is always exported (with "?" for the unknown ones)
+*/
+type Game struct {
+	Tags  map[string]string
+	Moves []GameMove
+}
+
+// Creates the PGN record of an unfinished game starting from FEN. Adds the SetUp and FEN tags when it is not the start position
+func NewPGNGame(fen string) *Game {
+	g := &Game{Tags: map[string]string{"Result": "*"}}
+
+	fields := strings.Fields(fen)
+	start := strings.Fields(START_FEN)
+	if len(fields) < 4 || strings.Join(fields[:4], " ") != strings.Join(start[:4], " ") {
+		g.Tags["SetUp"] = "1"
+		g.Tags["FEN"] = fen
+	}
+	return g
+}
+
+// Appends a move in SAN followed by COMMENT (empty for none)
+func (g *Game) AddMove(san string, comment string) {
+	g.Moves = append(g.Moves, GameMove{SAN: san, Comment: comment})
+}
+
+// Returns the result of the game: 1-0, 0-1, 1/2-1/2 or * if unfinished
+func (g *Game) Result() string {
+	if result, ok := g.Tags["Result"]; ok {
+		return result
+	}
+	return "*"
+}
+
+// Sets the result of the game
+func (g *Game) SetResult(result string) {
+	g.Tags["Result"] = result
+}
+
+// Side to move and move number of the first move of the game
+func (g *Game) firstMove() (bool, int) {
+	fields := strings.Fields(g.Tags["FEN"])
+	if len(fields) < 2 {
+		return true, 1
+	}
+	number := 1
+	if len(fields) > 5 {
+		if n, err := strconv.Atoi(fields[5]); err == nil && n > 0 {
+			number = n
+		}
+	}
+	return fields[1] != "b", number
+}
+
+// Quotes a tag value, escaping quotes and backslashes
+func quoteTag(value string) string {
+	value = strings.ReplaceAll(value, "\\", "\\\\")
+	return "\"" + strings.ReplaceAll(value, "\"", "\\\"") + "\""
+}
+
+// Exports the game in PGN (export format)
+func (g *Game) PGN() string {
+	var sb strings.Builder
+
+	// Seven Tag Roster first, then every other tag sorted
+	for _, tag := range sevenTagRoster {
+		value, ok := g.Tags[tag]
+		if !ok {
+			value = "?"
+			if tag == "Date" {
+				value = "????.??.??"
+			} else if tag == "Result" {
+				value = g.Result()
+			}
+		}
+		fmt.Fprintf(&sb, "[%s %s]\n", tag, quoteTag(value))
+	}
+	others := []string{}
+	for tag := range g.Tags {
+		known := false
+		for _, roster := range sevenTagRoster {
+			if tag == roster {
+				known = true
+			}
+		}
+		if !known {
+			others = append(others, tag)
+		}
+	}
+	sort.Strings(others)
+	for _, tag := range others {
+		fmt.Fprintf(&sb, "[%s %s]\n", tag, quoteTag(g.Tags[tag]))
+	}
+	sb.WriteString("\n")
+
+	// Movetext. A black move gets its number after a comment or at the start
+	tokens := []string{}
+	white, number := g.firstMove()
+	numbered := false
+	for _, move := range g.Moves {
+		if white {
+			tokens = append(tokens, fmt.Sprintf("%d.", number))
+		} else if !numbered {
+			tokens = append(tokens, fmt.Sprintf("%d...", number))
+		}
+		tokens = append(tokens, move.SAN)
+		for _, nag := range move.NAGs {
+			tokens = append(tokens, fmt.Sprintf("$%d", nag))
+		}
+		numbered = move.Comment == ""
+		if !numbered {
+			tokens = append(tokens, "{"+strings.ReplaceAll(move.Comment, "}", ")")+"}")
+		}
+
+		if !white {
+			number++
+		}
+		white = !white
+	}
+	tokens = append(tokens, g.Result())
+
+	line := 0
+	for i, token := range tokens {
+		if i > 0 {
+			if line+1+len(token) > PGN_LINE_LENGTH {
+				sb.WriteString("\n")
+				line = 0
+			} else {
+				sb.WriteString(" ")
+				line++
+			}
+		}
+		sb.WriteString(token)
+		line += len(token)
+	}
+	sb.WriteString("\n\n")
+	return sb.String()
+}
+
+// Suffix annotations and the NAG each one stands for
+var suffixAnnotations = map[string]int{"!": 1, "?": 2, "!!": 3, "??": 4, "!?": 5, "?!": 6}
+
+// Returned when a move of a game cannot be played. PLY starts at 1
+type IllegalMoveError struct {
+	Ply  int
+	Move string
+	Err  error
+}
+
+func (e *IllegalMoveError) Error() string {
+	return fmt.Sprintf("ply %d (%s): %v", e.Ply, e.Move, e.Err)
+}
+
+func (e *IllegalMoveError) Unwrap() error {
+	return e.Err
+}
+
+// Reads PGN text one game at a time
+type pgnParser struct {
+	text string
+	pos  int
+}
+
+/*
+	Parses every game of a PGN text. Comments and NAGs are attached to the move
+	they follow and variations are skipped, only the main line is kept
+*/
+func ParsePGN(text string) ([]*Game, error) {
+	p := pgnParser{text: text}
+	games := []*Game{}
+	for {
+		game, err := p.game()
+		if err != nil {
+			return games, fmt.Errorf("game %d: %w", len(games)+1, err)
+		}
+		if game == nil {
+			return games, nil
+		}
+		games = append(games, game)
+	}
+}
+
+// Returns the next game, nil when there are none left
+func (p *pgnParser) game() (*Game, error) {
+	g := &Game{Tags: map[string]string{}}
+	empty := true
+	variation := 0
+
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.text) {
+			break
+		}
+
+		char := p.text[p.pos]
+		switch {
+		case char == '%' && (p.pos == 0 || p.text[p.pos-1] == '\n'):
+			// Escaped line
+			p.skipLine()
+		case char == ';':
+			comment := p.skipLine()
+			if variation == 0 {
+				g.comment(strings.TrimSpace(comment[1:]))
+			}
+		case char == '{':
+			end := strings.IndexByte(p.text[p.pos:], '}')
+			if end == -1 {
+				return nil, fmt.Errorf("unterminated comment")
+			}
+			comment := p.text[p.pos+1 : p.pos+end]
+			p.pos += end + 1
+			if variation == 0 {
+				g.comment(strings.Join(strings.Fields(comment), " "))
+			}
+		case char == '[':
+			// A tag after the movetext belongs to the next game
+			if len(g.Moves) > 0 {
+				return g, nil
+			}
+			name, value, err := p.tag()
+			if err != nil {
+				return nil, err
+			}
+			g.Tags[name] = value
+			empty = false
+		case char == '(':
+			p.pos++
+			variation++
+		case char == ')':
+			p.pos++
+			if variation == 0 {
+				return nil, fmt.Errorf("unbalanced ')'")
+			}
+			variation--
+		case char == '$':
+			p.pos++
+			nag, err := strconv.Atoi(p.symbol())
+			if err != nil {
+				return nil, fmt.Errorf("invalid NAG")
+			}
+			if variation == 0 {
+				g.nag(nag)
+			}
+		default:
+			token := p.symbol()
+			if token == "" {
+				return nil, fmt.Errorf("unexpected character %q", char)
+			}
+			if variation > 0 {
+				continue
+			}
+
+			// Game termination marker
+			if token == "1-0" || token == "0-1" || token == "1/2-1/2" || token == "*" {
+				if _, ok := g.Tags["Result"]; !ok {
+					g.Tags["Result"] = token
+				}
+				return g, nil
+			}
+
+			// Move number indications (12. 12... 12.e4), castling may be written with zeros (0-0)
+			number := strings.TrimLeft(token, "0123456789")
+			if number == "" || strings.HasPrefix(number, ".") {
+				token = strings.TrimLeft(number, ".")
+			}
+			if token == "" {
+				continue
+			}
+
+			san := strings.TrimRight(token, "!?")
+			nag, annotated := suffixAnnotations[token[len(san):]]
+			g.AddMove(san, "")
+			if annotated {
+				g.nag(nag)
+			}
+			empty = false
+		}
+	}
+
+	if variation > 0 {
+		return nil, fmt.Errorf("unterminated variation")
+	}
+	if empty {
+		return nil, nil
+	}
+	return g, nil
+}
+
+// Skips whitespace
+func (p *pgnParser) skipSpace() {
+	for p.pos < len(p.text) && strings.IndexByte(" \t\r\n", p.text[p.pos]) != -1 {
+		p.pos++
+	}
+}
+
+// Skips to the start of the next line and returns the skipped text
+func (p *pgnParser) skipLine() string {
+	start := p.pos
+	end := strings.IndexByte(p.text[p.pos:], '\n')
+	if end == -1 {
+		p.pos = len(p.text)
+	} else {
+		p.pos += end + 1
+	}
+	return strings.TrimRight(p.text[start:p.pos], "\r\n")
+}
+
+// Reads a symbol (move, move number, result or tag name)
+func (p *pgnParser) symbol() string {
+	start := p.pos
+	for p.pos < len(p.text) && strings.IndexByte(" \t\r\n{}()[];$\"", p.text[p.pos]) == -1 {
+		p.pos++
+	}
+	return p.text[start:p.pos]
+}
+
+// Reads a tag pair: [Name "value"]
+func (p *pgnParser) tag() (string, string, error) {
+	p.pos++
+	p.skipSpace()
+	name := p.symbol()
+	p.skipSpace()
+	if name == "" || p.pos >= len(p.text) || p.text[p.pos] != '"' {
+		return "", "", fmt.Errorf("invalid tag")
+	}
+	p.pos++
+
+	var value strings.Builder
+	for {
+		if p.pos >= len(p.text) {
+			return "", "", fmt.Errorf("unterminated tag %s", name)
+		}
+		char := p.text[p.pos]
+		p.pos++
+		if char == '"' {
+			break
+		}
+		if char == '\\' && p.pos < len(p.text) {
+			char = p.text[p.pos]
+			p.pos++
+		}
+		value.WriteByte(char)
+	}
+
+	p.skipSpace()
+	if p.pos >= len(p.text) || p.text[p.pos] != ']' {
+		return "", "", fmt.Errorf("unterminated tag %s", name)
+	}
+	p.pos++
+	return name, value.String(), nil
+}
+
+// Attaches a comment to the last move. Comments before the first move are dropped
+func (g *Game) comment(comment string) {
+	if len(g.Moves) == 0 || comment == "" {
+		return
+	}
+	last := &g.Moves[len(g.Moves)-1]
+	if last.Comment != "" {
+		last.Comment += " "
+	}
+	last.Comment += comment
+}
+
+// Attaches a Numeric Annotation Glyph to the last move
+func (g *Game) nag(nag int) {
+	if len(g.Moves) == 0 {
+		return
+	}
+	last := &g.Moves[len(g.Moves)-1]
+	last.NAGs = append(last.NAGs, nag)
+}
+
+/*
+	Plays the moves of the game from its start position (the FEN tag if there is
+	one). Returns the position before the first move followed by the position after
+	every ply. On an illegal move the positions up to it are returned with an
+	*IllegalMoveError
+*/
+func (g *Game) Replay() ([]Chessboard, error) {
+	fen := START_FEN
+	if setup, ok := g.Tags["FEN"]; ok {
+		fen = setup
+	}
+
+	board := Chessboard{}
+	board.FromFen(fen)
+	positions := []Chessboard{board.Duplicate()}
+	for ply, move := range g.Moves {
+		if err := board.MakeSANMove(move.SAN); err != nil {
+			return positions, &IllegalMoveError{Ply: ply + 1, Move: move.SAN, Err: err}
+		}
+		positions = append(positions, board.Duplicate())
+	}
+	return positions, nil
+}
+
+// Replays the game and returns the FEN of the final position
+func (g *Game) FinalFen() (string, error) {
+	positions, err := g.Replay()
+	return positions[len(positions)-1].Fen(), err
+}
@@ -0,0 +1,216 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGamePGN(t *testing.T) {
+	g := NewPGNGame(START_FEN)
+	g.Tags["White"] = "Player"
+	g.Tags["Black"] = "Bot"
+	g.Tags["Annotator"] = "test"
+	g.AddMove("e4", "")
+	g.AddMove("e5", "depth 5, eval -0.10")
+	g.AddMove("Qh5", "")
+	g.AddMove("Nc6", "")
+	g.AddMove("Bc4", "")
+	g.AddMove("Nf6", "")
+	g.AddMove("Qxf7#", "")
+	g.SetResult("1-0")
+
+	want := `[Event "?"]
+[Site "?"]
+[Date "????.??.??"]
+[Round "?"]
+[White "Player"]
+[Black "Bot"]
+[Result "1-0"]
+[Annotator "test"]
+
+1. e4 e5 {depth 5, eval -0.10} 2. Qh5 Nc6 3. Bc4 Nf6 4. Qxf7# 1-0
+
+`
+	if pgn := g.PGN(); pgn != want {
+		t.Errorf("PGN =\n%s\nwant\n%s", pgn, want)
+	}
+}
+
+func TestGamePGNFromPosition(t *testing.T) {
+	fen := "4k3/8/8/8/8/8/4P3/4K3 b - - 0 12"
+	g := NewPGNGame(fen)
+	g.AddMove("Kd7", "depth 3, eval +1.00")
+	g.AddMove("e4", "")
+	g.AddMove("Ke6", "")
+
+	pgn := g.PGN()
+	for _, want := range []string{"[SetUp \"1\"]\n", "[FEN \"" + fen + "\"]\n", "[Result \"*\"]\n", "12... Kd7 {depth 3, eval +1.00} 13. e4 Ke6 *\n"} {
+		if !strings.Contains(pgn, want) {
+			t.Errorf("PGN does not contain %q:\n%s", want, pgn)
+		}
+	}
+	if strings.Contains(NewPGNGame(START_FEN).PGN(), "SetUp") {
+		t.Errorf("game from the start position has a SetUp tag")
+	}
+}
+
+func TestGamePGNLineLength(t *testing.T) {
+	g := NewPGNGame(START_FEN)
+	for i := 0; i < 50; i++ {
+		g.AddMove("Nf3", "")
+		g.AddMove("Nf6", "")
+		g.AddMove("Ng1", "")
+		g.AddMove("Ng8", "depth 5, eval +0.00")
+	}
+	g.Tags["Event"] = "Quote \" and backslash \\"
+
+	pgn := g.PGN()
+	for _, line := range strings.Split(pgn, "\n") {
+		if len(line) > PGN_LINE_LENGTH {
+			t.Errorf("line longer than %d: %q", PGN_LINE_LENGTH, line)
+		}
+	}
+	if !strings.Contains(pgn, `[Event "Quote \" and backslash \\"]`) {
+		t.Errorf("tag value not escaped:\n%s", pgn)
+	}
+	if !strings.HasSuffix(pgn, " *\n\n") && !strings.HasSuffix(pgn, "\n*\n\n") {
+		t.Errorf("PGN does not end with the result:\n%s", pgn)
+	}
+}
+
+const pgnGames = `[Event "Opera Game"]
+[Site "Paris FRA"]
+[Date "1858.??.??"]
+[Round "?"]
+[White "Paul Morphy"]
+[Black "Duke Karl / Count Isouard"]
+[Result "1-0"]
+
+1. e4 e5 2. Nf3 d6 3. d4 Bg4 $6 {This is a weak move
+already.} 4. dxe5 Bxf3 5. Qxf3 dxe5 6. Bc4 Nf6 7. Qb3 Qe7 8. Nc3 c6 9. Bg5 b5
+(9... Qb4 10. Qxb4 (10. Bxf6 {The other way}) 10... Bxb4) 10. Nxb5! cxb5
+11. Bxb5+ Nbd7 12. O-O-O Rd8 13. Rxd7 Rxd7 14. Rd1 Qe6 15. Bxd7+ Nxd7
+16. Qb8+ Nxb8 17. Rd8# 1-0
+
+% An escaped line
+[Event "From a position"]
+[SetUp "1"]
+[FEN "4k3/8/8/8/8/8/4P3/4K3 b - - 0 12"]
+[Result "*"]
+
+12... Kd7 ; rest of line comment
+13.e4 Ke6!? *
+`
+
+func TestParsePGN(t *testing.T) {
+	games, err := ParsePGN(pgnGames)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(games) != 2 {
+		t.Fatalf("parsed %d games, want 2", len(games))
+	}
+
+	opera := games[0]
+	if opera.Tags["White"] != "Paul Morphy" || opera.Result() != "1-0" {
+		t.Errorf("tags = %v", opera.Tags)
+	}
+	if len(opera.Moves) != 33 {
+		t.Fatalf("parsed %d moves, want 33", len(opera.Moves))
+	}
+	if m := opera.Moves[5]; m.SAN != "Bg4" || m.Comment != "This is a weak move already." || len(m.NAGs) != 1 || m.NAGs[0] != 6 {
+		t.Errorf("move 6 = %+v", m)
+	}
+	if m := opera.Moves[18]; m.SAN != "Nxb5" || len(m.NAGs) != 1 || m.NAGs[0] != 1 {
+		t.Errorf("move 19 = %+v", m)
+	}
+
+	positions, err := opera.Replay()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(positions) != 34 {
+		t.Errorf("replay returned %d positions, want 34", len(positions))
+	}
+	final := positions[len(positions)-1]
+	if len(final.PossibleMoves(final.ToMove())) != 0 || final.VerifyState(final.ToMove()) {
+		t.Errorf("final position is not checkmate: %s", final.Fen())
+	}
+
+	fromPosition := games[1]
+	if fromPosition.Moves[0].Comment != "rest of line comment" || fromPosition.Moves[2].NAGs[0] != 5 {
+		t.Errorf("moves = %+v", fromPosition.Moves)
+	}
+	fen, err := fromPosition.FinalFen()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Chessboard{}
+	want.FromFen("8/8/4k3/8/4P3/8/8/4K3 w - - 1 14")
+	if fen != want.Fen() {
+		t.Errorf("final fen = %s, want %s", fen, want.Fen())
+	}
+}
+
+func TestParsePGNZeroCastling(t *testing.T) {
+	games, err := ParsePGN("1. e4 e5 2. Nf3 Nc6 3. Bc4 d6 4. 0-0 Be6 5. d3 Qd7 6. Nc3 0-0-0 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fen, err := games[0].FinalFen()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Chessboard{}
+	want.FromFen("2kr1bnr/pppq1ppp/2npb3/4p3/2B1P3/2NP1N2/PPP2PPP/R1BQ1RK1 w - - 3 7")
+	if fen != want.Fen() {
+		t.Errorf("final fen = %s, want %s", fen, want.Fen())
+	}
+}
+
+func TestParsePGNRoundTrip(t *testing.T) {
+	games, err := ParsePGN(pgnGames)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := ParsePGN(games[0].PGN() + games[1].PGN())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range games {
+		if games[i].PGN() != again[i].PGN() {
+			t.Errorf("game %d changed after a round trip:\n%s\n%s", i+1, games[i].PGN(), again[i].PGN())
+		}
+	}
+}
+
+func TestReplayIllegalMove(t *testing.T) {
+	games, err := ParsePGN("1. e4 e5 2. Nf3 Nf6 3. Ke3 Nc6 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	positions, err := games[0].Replay()
+	illegal, ok := err.(*IllegalMoveError)
+	if !ok {
+		t.Fatalf("Replay error = %v, want an IllegalMoveError", err)
+	}
+	if illegal.Ply != 5 || illegal.Move != "Ke3" {
+		t.Errorf("illegal move = %s at ply %d, want Ke3 at ply 5", illegal.Move, illegal.Ply)
+	}
+	if len(positions) != 5 {
+		t.Errorf("replay returned %d positions, want 5", len(positions))
+	}
+}
+
+func TestParsePGNErrors(t *testing.T) {
+	for _, text := range []string{
+		"1. e4 {unterminated",
+		"[Event \"unterminated",
+		"1. e4 (1. d4 *",
+		"1. e4 ) *",
+	} {
+		if _, err := ParsePGN(text); err == nil {
+			t.Errorf("ParsePGN(%q) succeeded, want an error", text)
+		}
+	}
+}
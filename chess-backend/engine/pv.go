@@ -0,0 +1,43 @@
+package engine
+
+/*
+	Principal variation of every ply of a search. The line of a ply is its
+	best move followed by the line of the next ply, copied when the move
+	becomes the best one, so that the line of the root is the expected game
+*/
+type pvTable struct {
+	moves  [MAX_PLY][MAX_PLY]PossibleMove
+	length [MAX_PLY]int
+}
+
+// Empties the line of PLY
+func (p *pvTable) clear(ply int) {
+	if ply < MAX_PLY {
+		p.length[ply] = 0
+	}
+}
+
+// Makes MOVE the best one of PLY, followed by the line of the next ply
+func (p *pvTable) update(ply int, move PossibleMove) {
+	if ply >= MAX_PLY {
+		return
+	}
+	p.moves[ply][0] = move
+	p.length[ply] = 1
+	if ply+1 < MAX_PLY {
+		p.length[ply] += copy(p.moves[ply][1:], p.moves[ply+1][:p.length[ply+1]])
+	}
+}
+
+// Replaces the line of PLY with MOVE alone, the rest of the line is unknown
+func (p *pvTable) set(ply int, move PossibleMove) {
+	if ply < MAX_PLY {
+		p.moves[ply][0] = move
+		p.length[ply] = 1
+	}
+}
+
+// Copy of the line of the root
+func (p *pvTable) line() []PossibleMove {
+	return append([]PossibleMove{}, p.moves[0][:p.length[0]]...)
+}
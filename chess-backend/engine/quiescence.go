@@ -0,0 +1,121 @@
+package engine
+
+import (
+	"math"
+	"sort"
+)
+
+// Plies of the quiescence search where a side in check searches every evasion
+const QUIESCENCE_EVASION_PLIES = 1
+
+// Margin over the material won for a capture to be searched by the quiescence search
+const DELTA_MARGIN = 200
+
+// Material value of the PIECE of TEAM
+func (c *Chessboard) pieceValue(piece int, team bool) float64 {
+	name := c.PieceName(piece, team)
+	return float64(pieceValue[pieceIndexMap[name[0]][0]])
+}
+
+// Value of the material PM wins: the captured piece plus what a promotion adds to the pawn
+func (c *Chessboard) materialGain(pm PossibleMove, team bool) float64 {
+	gain := 0.0
+	if pm.target != 0 {
+		gain += c.pieceValue(int(pm.target&0x1F), !team)
+	}
+	if pm.promote {
+		gain += float64(pieceValue[pieceIndexMap[pm.promote_to][0]] - pieceValue[8])
+	}
+	return gain
+}
+
+/*
+	Sorts the captures PM of TEAM, most valuable victim first and least
+	valuable attacker first among equal victims
+*/
+func (c *Chessboard) orderCaptures(pm []PossibleMove, team bool) []PossibleMove {
+	for i := range pm {
+		pm[i].score = c.materialGain(pm[i], team)*10 - c.pieceValue(pm[i].piece, team)/100
+	}
+	sort.SliceStable(pm, func(i, j int) bool {
+		return pm[i].score > pm[j].score
+	})
+	return pm
+}
+
+/*
+	Searches captures and promotions until the position is quiet, so that the
+	evaluation of a leaf is not taken in the middle of an exchange. The side to
+	move may stand pat on the static evaluation instead of capturing. A side in
+	check searches every evasion instead. PLY counts from the root and QPLY
+	from the start of the quiescence search
+*/
+func (e *Engine) quiescence(c *Chessboard, ply int, qply int, alfa float64, beta float64, team bool, num_states *int) float64 {
+	*num_states += 1
+
+	// Dead position, reached at the horizon or by a capture
+	if c.InsufficientMaterial() {
+		return 0
+	}
+
+	in_check := qply < QUIESCENCE_EVASION_PLIES && !c.VerifyState(team)
+
+	// Stand pat
+	best := math.Inf(-1)
+	if !team {
+		best = math.Inf(+1)
+	}
+	stand_pat := 0.0
+	if !in_check {
+		stand_pat = c.Evaluate()
+		best = stand_pat
+		if team {
+			if stand_pat >= beta {
+				return stand_pat
+			}
+			alfa = math.Max(alfa, stand_pat)
+		} else {
+			if stand_pat <= alfa {
+				return stand_pat
+			}
+			beta = math.Min(beta, stand_pat)
+		}
+	}
+
+	var pm []PossibleMove
+	if in_check {
+		pm = c.PossibleMoves(team)
+		if len(pm) == 0 {
+			return matedScore(team, ply)
+		}
+	} else {
+		pm = c.orderCaptures(c.PossibleCaptures(team), team)
+	}
+
+	var board Chessboard
+	for _, state := range pm {
+		if !in_check {
+			// Delta pruning: not even winning the piece gets the score back into the window
+			gain := c.materialGain(state, team) + DELTA_MARGIN
+			if team && stand_pat+gain <= alfa || !team && stand_pat-gain >= beta {
+				continue
+			}
+		}
+
+		c.SaveState(&board)
+		board.MakeUnsafeMove(state, team)
+		score := e.quiescence(&board, ply+1, qply+1, alfa, beta, !team, num_states)
+
+		if team {
+			best = math.Max(best, score)
+			alfa = math.Max(alfa, score)
+		} else {
+			best = math.Min(best, score)
+			beta = math.Min(beta, score)
+		}
+		if beta <= alfa {
+			break
+		}
+	}
+	return best
+}
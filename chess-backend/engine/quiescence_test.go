@@ -0,0 +1,75 @@
+package engine
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// Captures and promotions of PM in UCI notation, sorted
+func captureList(c *Chessboard, pm []PossibleMove) []string {
+	moves := []string{}
+	for _, state := range pm {
+		if state.Capture() || state.promote {
+			moves = append(moves, c.MoveUCI(state))
+		}
+	}
+	sort.Strings(moves)
+	return moves
+}
+
+func TestPossibleCaptures(t *testing.T) {
+	check := func(c *Chessboard, name string) {
+		team := c.ToMove()
+		want := captureList(c, c.PossibleMoves(team))
+		got := captureList(c, c.PossibleCaptures(team))
+		if len(got) != len(c.PossibleCaptures(team)) || strings.Join(got, " ") != strings.Join(want, " ") {
+			t.Errorf("%s: captures %v, want %v", name, got, want)
+		}
+	}
+
+	// Every position of the perft cases and the positions one move away
+	var board Chessboard
+	for _, tc := range perftCases {
+		root := Chessboard{}
+		root.FromFen(tc.fen)
+		check(&root, tc.name)
+		for _, pm := range root.PossibleMoves(root.ToMove()) {
+			root.SaveState(&board)
+			board.MakeUnsafeMove(pm, root.ToMove())
+			check(&board, tc.name+" "+root.MoveUCI(pm))
+		}
+	}
+}
+
+func TestQuiescence(t *testing.T) {
+	e := NewEngine()
+	board := Chessboard{}
+
+	// Nothing to capture, the score is the evaluation
+	board.FromFen(START_FEN)
+	states := 0
+	if score := e.quiescence(&board, 0, 0, math.Inf(-1), math.Inf(+1), true, &states); score != board.Evaluate() {
+		t.Errorf("quiet position scored %.2f, want the evaluation %.2f", score, board.Evaluate())
+	}
+
+	// The queen can take a pawn but the recapture loses her
+	board.FromFen("4k3/8/3p4/4p3/8/8/4Q3/4K3 w - - 0 1")
+	states = 0
+	score := e.quiescence(&board, 0, 0, math.Inf(-1), math.Inf(+1), true, &states)
+	if score != board.Evaluate() {
+		t.Errorf("score %.2f, want to stand pat at %.2f", score, board.Evaluate())
+	}
+}
+
+func TestSearchHorizon(t *testing.T) {
+	board := Chessboard{}
+	board.FromFen("4k3/8/3p4/4p3/8/8/4Q3/4K3 w - - 0 1")
+
+	states := 0
+	_, pm := NewEngine().Minimax(&board, 1, math.Inf(-1), math.Inf(+1), true, &states)
+	if move := board.MoveUCI(pm); move == "e2e5" {
+		t.Errorf("the queen takes a defended pawn")
+	}
+}
@@ -0,0 +1,64 @@
+package engine
+
+// Returns the en passant square if TEAM has a legal en passant capture, 0 otherwise
+func (c *Chessboard) enpassantCapture(team bool) uint8 {
+	if (c.enpassant&(1<<7))>>7 != 1 {
+		return 0
+	}
+	ep := Location{}
+	ep.fromByte(c.enpassant)
+
+	pieces := c.black
+	pawn_y := ep.y + 1
+	if team {
+		pieces = c.white
+		pawn_y = ep.y - 1
+	}
+
+	var board Chessboard
+	for idx := 8; idx < 16; idx++ {
+		if pieces[idx] == 0 {
+			continue
+		}
+		l := Location{}
+		l.fromByte(uint8(pieces[idx]))
+		if l.y != pawn_y || (l.x != ep.x-1 && l.x != ep.x+1) {
+			continue
+		}
+
+		c.SaveState(&board)
+		if ok, _ := board.TestMove(uint8(idx), team, ep, 'q'); ok {
+			return c.enpassant
+		}
+	}
+	return 0
+}
+
+// Pushes the current position to the history
+func (c *Chessboard) recordPosition() {
+	c.history = append(c.history, c.hash)
+}
+
+/*
+	Number of times the current position appeared in the game and the line being
+	searched, counting itself. Positions are compared by their Zobrist hash, only
+	the ones since the last capture or pawn move (with the same team to move)
+*/
+func (c *Chessboard) Repetitions() int {
+	if len(c.history) == 0 {
+		return 1
+	}
+	last := len(c.history) - 1
+	count := 0
+	for i := last; i >= 0 && last-i <= c.mc; i -= 2 {
+		if c.history[i] == c.history[last] {
+			count++
+		}
+	}
+	return count
+}
+
+// Returns TRUE if the current position appeared three times, so a draw can be claimed
+func (c *Chessboard) ThreefoldRepetition() bool {
+	return c.Repetitions() >= 3
+}
@@ -0,0 +1,96 @@
+package engine
+
+import "testing"
+
+func TestThreefoldRepetition(t *testing.T) {
+	board := Chessboard{}
+	board.FromFen(START_FEN)
+
+	shuffle := []string{"g1f3", "g8f6", "f3g1", "f6g8"}
+	for i, move := range append(shuffle, shuffle...) {
+		if board.ThreefoldRepetition() {
+			t.Fatalf("threefold repetition after %d plies", i)
+		}
+		if !board.MakeUCIMove(move) {
+			t.Fatalf("%s is not legal", move)
+		}
+	}
+	if board.Repetitions() != 3 || !board.ThreefoldRepetition() {
+		t.Errorf("repetitions = %d, want 3", board.Repetitions())
+	}
+
+	// A pawn move starts over
+	board.MakeUCIMove("e2e4")
+	if board.Repetitions() != 1 {
+		t.Errorf("repetitions after a pawn move = %d, want 1", board.Repetitions())
+	}
+}
+
+func TestRepetitionCastlingRights(t *testing.T) {
+	board := Chessboard{}
+	board.FromFen("r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1")
+
+	// Same squares, but the rooks lost their castling rights
+	for _, move := range []string{"a1b1", "a8b8", "b1a1", "b8a8"} {
+		board.MakeUCIMove(move)
+	}
+	if board.Repetitions() != 1 {
+		t.Errorf("repetitions = %d, want 1", board.Repetitions())
+	}
+	for _, move := range []string{"a1b1", "a8b8", "b1a1", "b8a8"} {
+		board.MakeUCIMove(move)
+	}
+	if board.Repetitions() != 2 {
+		t.Errorf("repetitions = %d, want 2", board.Repetitions())
+	}
+}
+
+func TestRepetitionEnPassant(t *testing.T) {
+	with := Chessboard{}
+	without := Chessboard{}
+
+	// No black pawn can take on e3, the square does not count
+	with.FromFen("4k3/8/8/8/4P3/8/8/4K3 b - e3 0 1")
+	without.FromFen("4k3/8/8/8/4P3/8/8/4K3 b - - 0 1")
+	if with.Hash() != without.Hash() {
+		t.Errorf("unusable en passant square changes the position")
+	}
+
+	with.FromFen("4k3/8/8/8/3pP3/8/8/4K3 b - e3 0 1")
+	without.FromFen("4k3/8/8/8/3pP3/8/8/4K3 b - - 0 1")
+	if with.Hash() == without.Hash() {
+		t.Errorf("legal en passant capture does not change the position")
+	}
+
+	// The capturing pawn is pinned
+	with.FromFen("8/8/8/8/k2pP2R/8/8/4K3 b - e3 0 1")
+	without.FromFen("8/8/8/8/k2pP2R/8/8/4K3 b - - 0 1")
+	if with.Hash() != without.Hash() {
+		t.Errorf("illegal en passant capture changes the position")
+	}
+}
+
+func TestRepetitionAlongLine(t *testing.T) {
+	board := Chessboard{}
+	board.FromFen("4k3/8/8/8/8/8/8/4K3 w - - 0 1")
+
+	// Moves made while searching are part of the line
+	line := []string{"e1d1", "e8d8", "d1e1", "d8e8"}
+	boards := make([]Chessboard, len(line)+1)
+	boards[0] = board
+	team := true
+	for i, move := range line {
+		pm := moveByUCI(t, &boards[i], move)
+		boards[i].SaveState(&boards[i+1])
+		boards[i+1].MakeUnsafeMove(pm, team)
+		team = !team
+	}
+	if n := boards[len(line)].Repetitions(); n != 2 {
+		t.Errorf("repetitions along the line = %d, want 2", n)
+	}
+
+	// The parent does not see its children
+	if n := board.Repetitions(); n != 1 || len(board.history) != 1 {
+		t.Errorf("root repetitions = %d with %d positions, want 1 with 1", n, len(board.history))
+	}
+}
@@ -0,0 +1,432 @@
+package engine
+
+import (
+	"math"
+)
+
+// Width of the zero window of the principal variation search
+const PVS_WINDOW = 1
+
+// Depth the null move search skips on top of the move
+const NULL_MOVE_REDUCTION = 2
+const NULL_MOVE_MIN_DEPTH = 3
+
+// Pieces other than the king and pawns a side needs for the null move, with
+// fewer a single piece and pawns are often in zugzwang
+const NULL_MOVE_MIN_PIECES = 2
+
+// Depth the search verifying a null move cut skips, deep enough to see a mate in 2 behind a zugzwang
+const NULL_MOVE_VERIFY_REDUCTION = 1
+
+// Moves searched to full depth before the late move reductions start
+const LMR_FULL_MOVES = 3
+const LMR_MIN_DEPTH = 3
+const LMR_REDUCTION = 1
+
+// Calculate the best movement for a TEAM, PLY moves away from the root
+func (e *Engine) minimax(c *Chessboard, depth int, ply int, alfa float64, beta float64, team bool, num_states *int) (float64, PossibleMove) {
+	e.pv.clear(ply)
+	if depth == 0 {
+		return e.quiescence(c, ply, 0, alfa, beta, team, num_states), PossibleMove{invalid: true}
+	}
+
+	// Dead position, nobody can win
+	if ply != 0 && c.InsufficientMaterial() {
+		return 0, PossibleMove{invalid: true}
+	}
+
+	zh := c.hash
+	// Window the score of this node is compared against when stored
+	alfa_orig, beta_orig := alfa, beta
+
+	// Root of a MultiPV line or search of the moves other than a singular
+	// candidate, the score is only the one of the moves left
+	excluding := ply == 0 && len(e.excluded) != 0 || ply < MAX_PLY && e.singular_search[ply]
+
+	// The stored move is searched first even if the entry is too shallow to use
+	hash_entry, hash_hit := e.transposition_table.probe(zh)
+
+	if ply != 0 {
+		// A position repeated in the game or along the line can be repeated again
+		if c.Repetitions() >= 2 {
+			return 0.01, PossibleMove{invalid: true}
+		}
+
+		// Fifty-move rule, unless the last move was checkmate
+		if c.FiftyMoveRule() && len(c.PossibleMoves(team)) != 0 {
+			return 0, PossibleMove{invalid: true}
+		}
+
+		// Mate distance pruning: nothing from here beats a mate found closer to the root
+		alfa = math.Max(alfa, matedScore(true, ply))
+		beta = math.Min(beta, matedScore(false, ply))
+		if beta <= alfa {
+			return alfa, PossibleMove{invalid: true}
+		}
+
+		if val := hash_entry; hash_hit && int(val.depth) >= depth && !excluding {
+			score := scoreFromTT(val.score, ply)
+			switch val.flag {
+			case TT_EXACT:
+				*num_states += 1
+				return score, PossibleMove{invalid: true}
+			case TT_LOWER:
+				alfa = math.Max(alfa, score)
+			case TT_UPPER:
+				beta = math.Min(beta, score)
+			}
+			if beta <= alfa {
+				*num_states += 1
+				return score, PossibleMove{invalid: true}
+			}
+		}
+	}
+
+	in_check := !c.VerifyState(team)
+
+	// Null-move pruning: if passing the turn still fails high, a move will too.
+	// Not done twice in a row, in check or with few pieces, where passing can be the best move (zugzwang),
+	// nor in the search that verifies a null move of this ply
+	null_allowed := ply > 0 && (ply >= MAX_PLY || !e.null_move[ply-1] && !e.null_move[ply])
+	if e.Options.NullMove && null_allowed && depth >= NULL_MOVE_MIN_DEPTH && !in_check && c.NonPawnPieces(team) >= NULL_MOVE_MIN_PIECES {
+		if score, ok := e.nullMove(c, depth, ply, alfa, beta, team, num_states); ok {
+			return score, PossibleMove{invalid: true}
+		}
+		if e.Stopped() {
+			return 0, PossibleMove{invalid: true}
+		}
+	}
+
+	// The hash move is extended if no other move comes close
+	singular := false
+	if e.Options.SingularExtension && ply > 0 && depth >= SINGULAR_MIN_DEPTH && !excluding && hash_hit && hash_entry.piece >= 0 && int(hash_entry.depth) >= depth-SINGULAR_DEPTH_MARGIN {
+		singular = e.singularMove(c, depth, ply, hash_entry, team, num_states)
+		if e.Stopped() {
+			return 0, PossibleMove{invalid: true}
+		}
+		// The line of the search without the hash move is not the one of this node
+		e.pv.clear(ply)
+	}
+
+	// A child was scored as a repetition, the result depends on the line played.
+	// A node without some of its moves is not stored either
+	path_dependent := excluding
+
+	// Root moves close enough to the best one to be played instead of it
+	margin := e.varietyMargin(ply)
+	candidates := []rootMove{}
+
+	var board Chessboard
+	if team {
+		maxEval := math.Inf(-1)
+		var maxEvalState PossibleMove
+		// First move scored as a repetition
+		repetition := PossibleMove{invalid: true}
+		pm := c.PossibleMoves(team)
+		if len(pm) == 0 {
+
+			if in_check {
+				// White is checkmated
+				return matedScore(team, ply), PossibleMove{invalid: true}
+			} else {
+				// White has no legal moves
+				return 0, PossibleMove{invalid: true}
+			}
+		}
+
+		if excluding {
+			pm = e.withoutExcluded(pm, ply)
+		}
+		e.orderMoves(c, pm, team, ply, hash_entry)
+		for i, state := range pm {
+			*num_states += 1
+			c.SaveState(&board)
+			board.MakeUnsafeMove(state, team)
+			score := e.searchMove(&board, state, i, depth, ply, alfa-margin, beta, team, in_check, singular && hash_entry.isMove(state), num_states)
+
+			// Search was interrupted, the result is incomplete
+			if e.Stopped() {
+				return 0, PossibleMove{invalid: true}
+			}
+			if margin > 0 && !(score > 0 && score < 0.1) {
+				candidates = append(candidates, rootMove{state, score})
+			}
+			if score == math.Inf(-1) {
+				maxEval = score
+				maxEvalState = state
+				e.pv.update(ply, state)
+			} else if score > 0 && score < 0.1 {
+				// Just ignore it
+				path_dependent = true
+				if repetition.invalid {
+					repetition = state
+				}
+			} else if score > maxEval {
+				maxEval = score
+				maxEvalState = state
+				e.pv.update(ply, state)
+			}
+			alfa = math.Max(alfa, score)
+			if beta <= alfa {
+				e.recordCutoff(state, team, ply, depth, i)
+				break
+			}
+		}
+
+		// Every move searched was a repetition, the node is a draw along this line
+		if maxEval == math.Inf(-1) && !repetition.invalid {
+			return 0.01, repetition
+		}
+
+		e.storeTransposition(zh, maxEval, depth, ply, alfa_orig, beta_orig, maxEvalState, path_dependent)
+		if margin > 0 {
+			maxEvalState, maxEval = e.pickVariety(candidates, maxEvalState, maxEval, margin, team)
+		}
+		return maxEval, maxEvalState
+	} else {
+		minEval := math.Inf(+1)
+		var minEvalState PossibleMove
+		// First move scored as a repetition
+		repetition := PossibleMove{invalid: true}
+		pm := c.PossibleMoves(team)
+		if len(pm) == 0 {
+
+			if in_check {
+				// black is checkmated
+				return matedScore(team, ply), PossibleMove{invalid: true}
+			} else {
+				// black has no legal moves
+				return 0, PossibleMove{invalid: true}
+			}
+		}
+
+		if excluding {
+			pm = e.withoutExcluded(pm, ply)
+		}
+		e.orderMoves(c, pm, team, ply, hash_entry)
+		for i, state := range pm {
+			*num_states += 1
+			c.SaveState(&board)
+			board.MakeUnsafeMove(state, team)
+			score := e.searchMove(&board, state, i, depth, ply, alfa, beta+margin, team, in_check, singular && hash_entry.isMove(state), num_states)
+
+			// Search was interrupted, the result is incomplete
+			if e.Stopped() {
+				return 0, PossibleMove{invalid: true}
+			}
+			if margin > 0 && !(score > 0 && score < 0.1) {
+				candidates = append(candidates, rootMove{state, score})
+			}
+
+			if score == math.Inf(+1) {
+				minEval = score
+				minEvalState = state
+				e.pv.update(ply, state)
+			} else if score > 0 && score < 0.1 {
+				// Just ignore it
+				path_dependent = true
+				if repetition.invalid {
+					repetition = state
+				}
+			} else if score < minEval {
+				minEval = score
+				minEvalState = state
+				e.pv.update(ply, state)
+			}
+			beta = math.Min(beta, score)
+			if beta <= alfa {
+				e.recordCutoff(state, team, ply, depth, i)
+				break
+			}
+		}
+
+		// Every move searched was a repetition, the node is a draw along this line
+		if minEval == math.Inf(+1) && !repetition.invalid {
+			return 0.01, repetition
+		}
+
+		e.storeTransposition(zh, minEval, depth, ply, alfa_orig, beta_orig, minEvalState, path_dependent)
+		if margin > 0 {
+			minEvalState, minEval = e.pickVariety(candidates, minEvalState, minEval, margin, team)
+		}
+		return minEval, minEvalState
+	}
+}
+
+// Stores the SCORE of a search to DEPTH, PLY plies away from the root, with the bound given by the ALFA BETA window it was searched with
+func (e *Engine) storeTransposition(zh int64, score float64, depth int, ply int, alfa float64, beta float64, best PossibleMove, path_dependent bool) {
+	// Not a real score of the position
+	if path_dependent || math.IsInf(score, 0) {
+		return
+	}
+
+	flag := uint8(TT_EXACT)
+	if score <= alfa {
+		flag = TT_UPPER
+	} else if score >= beta {
+		flag = TT_LOWER
+	}
+	e.transposition_table.store(zh, scoreToTT(score, ply), depth, flag, best)
+}
+
+// Root move and its score
+type rootMove struct {
+	state PossibleMove
+	score float64
+}
+
+// Moves PM of PLY but the singular candidate, or the root moves not already in a MultiPV line
+func (e *Engine) withoutExcluded(pm []PossibleMove, ply int) []PossibleMove {
+	moves := []PossibleMove{}
+	for _, state := range pm {
+		if ply < MAX_PLY && e.singular_search[ply] {
+			if !e.singular[ply].isMove(state) {
+				moves = append(moves, state)
+			}
+			continue
+		}
+
+		excluded := false
+		for _, line := range e.excluded {
+			if state.piece == line.piece && state.end_pos == line.end_pos && state.promote_to == line.promote_to {
+				excluded = true
+				break
+			}
+		}
+		if !excluded {
+			moves = append(moves, state)
+		}
+	}
+	return moves
+}
+
+// Points a root move can lose and still be played, 0 below the root, without variety or with MultiPV
+func (e *Engine) varietyMargin(ply int) float64 {
+	if ply != 0 || e.Deterministic || e.Variety <= 0 || e.rng == nil || e.MultiPV > 1 {
+		return 0
+	}
+	return e.Variety
+}
+
+/*
+	Picks at random one of the root MOVES of TEAM scored less than MARGIN
+	points away from SCORE, the score of BEST. The window of the root is
+	widened by MARGIN so that these scores are exact and not just bounds.
+	Returns the move and its own score
+*/
+func (e *Engine) pickVariety(moves []rootMove, best PossibleMove, score float64, margin float64, team bool) (PossibleMove, float64) {
+	// Only the best way to mate (or to delay it) is played
+	if IsMate(score) {
+		return best, score
+	}
+
+	similar := []rootMove{}
+	for _, move := range moves {
+		if team && move.score > score-margin || !team && move.score < score+margin {
+			similar = append(similar, move)
+		}
+	}
+	if len(similar) == 0 {
+		return best, score
+	}
+	choice := similar[e.rng.Intn(len(similar))]
+	if choice.state != best {
+		e.pv.set(0, choice.state)
+	}
+	return choice.state, choice.score
+}
+
+/*
+	Searches the null move of TEAM, returns the score and TRUE if it fails high
+	(fails low for black) so that the node can be cut. The cut is verified by
+	a shallower search of the moves of TEAM, which fails high too unless TEAM
+	is in zugzwang
+*/
+func (e *Engine) nullMove(c *Chessboard, depth int, ply int, alfa float64, beta float64, team bool, num_states *int) (float64, bool) {
+	// Only worth trying when the position is already good enough
+	eval := c.Evaluate()
+	if team && eval < beta || !team && eval > alfa {
+		return 0, false
+	}
+
+	var board Chessboard
+	c.SaveState(&board)
+	board.makeNullMove()
+	if ply < MAX_PLY {
+		e.null_move[ply] = true
+		defer func() { e.null_move[ply] = false }()
+	}
+	if ply+1 < MAX_PLY {
+		e.extensions[ply+1] = e.extensions[ply]
+	}
+
+	if team {
+		score, _ := e.minimax(&board, depth-1-NULL_MOVE_REDUCTION, ply+1, beta-PVS_WINDOW, beta, !team, num_states)
+		if e.Stopped() || score < beta {
+			return 0, false
+		}
+		verify, _ := e.minimax(c, depth-NULL_MOVE_VERIFY_REDUCTION, ply, beta-PVS_WINDOW, beta, team, num_states)
+		if e.Stopped() || verify < beta {
+			return 0, false
+		}
+		// Only the bound is proven, a mate found after passing is not
+		return beta, true
+	}
+	score, _ := e.minimax(&board, depth-1-NULL_MOVE_REDUCTION, ply+1, alfa, alfa+PVS_WINDOW, !team, num_states)
+	if e.Stopped() || score > alfa {
+		return 0, false
+	}
+	verify, _ := e.minimax(c, depth-NULL_MOVE_VERIFY_REDUCTION, ply, alfa, alfa+PVS_WINDOW, team, num_states)
+	if e.Stopped() || verify > alfa {
+		return 0, false
+	}
+	return alfa, true
+}
+
+/*
+	Searches the I-th move STATE of TEAM, already made on BOARD. With PVS the
+	moves after the first are searched with a zero window that only tells if
+	they beat the best one so far, and searched again with the full window
+	if they do. With LMR late quiet moves are searched to a reduced depth first.
+	Checks, pawn pushes to the 7th and SINGULAR moves are extended instead
+*/
+func (e *Engine) searchMove(board *Chessboard, state PossibleMove, i int, depth int, ply int, alfa float64, beta float64, team bool, in_check bool, singular bool, num_states *int) float64 {
+	gives_check := !board.VerifyState(!team)
+	ext := e.extension(board, state, team, ply, gives_check, singular)
+	new_depth := depth - 1 + ext
+
+	if i == 0 || !e.Options.PVS && !e.Options.LMR {
+		score, _ := e.minimax(board, new_depth, ply+1, alfa, beta, !team, num_states)
+		return score
+	}
+
+	// Zero window on the bound TEAM has to beat
+	zero_alfa, zero_beta := alfa, alfa+PVS_WINDOW
+	if !team {
+		zero_alfa, zero_beta = beta-PVS_WINDOW, beta
+	}
+	improves := func(score float64) bool {
+		if team {
+			return score > alfa
+		}
+		return score < beta
+	}
+
+	// Quiet moves that do not give check and are not extended
+	if e.Options.LMR && i >= LMR_FULL_MOVES && depth >= LMR_MIN_DEPTH && !in_check && !state.tactical() && !gives_check && ext == 0 {
+		score, _ := e.minimax(board, new_depth-LMR_REDUCTION, ply+1, zero_alfa, zero_beta, !team, num_states)
+		if e.Stopped() || !improves(score) {
+			return score
+		}
+	}
+
+	if e.Options.PVS && beta-alfa > PVS_WINDOW {
+		score, _ := e.minimax(board, new_depth, ply+1, zero_alfa, zero_beta, !team, num_states)
+		if e.Stopped() || !improves(score) {
+			return score
+		}
+	}
+
+	score, _ := e.minimax(board, new_depth, ply+1, alfa, beta, !team, num_states)
+	return score
+}
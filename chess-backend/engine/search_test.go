@@ -0,0 +1,235 @@
+package engine
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNullMove(t *testing.T) {
+	board := Chessboard{}
+	board.FromFen("rnbqkbnr/ppp1pppp/8/8/3pP3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 3")
+	board.makeNullMove()
+	if board.ToMove() != true || board.enpassant != 0 {
+		t.Errorf("null move left black to move or the en passant square set")
+	}
+	if want := board.zobristHash(board.toMove); board.Hash() != want {
+		t.Errorf("hash after the null move = %x, want %x", board.Hash(), want)
+	}
+}
+
+func TestHasPieces(t *testing.T) {
+	board := Chessboard{}
+	board.FromFen("4k3/pppp4/8/8/8/8/4P3/4KN2 w - - 0 1")
+	if !board.HasPieces(true) || board.HasPieces(false) {
+		t.Errorf("white with a knight, black with pawns only: %t %t", board.HasPieces(true), board.HasPieces(false))
+	}
+	if n := board.NonPawnPieces(true); n != 1 {
+		t.Errorf("white pieces = %d, want the knight alone", n)
+	}
+
+	// Promoted pieces count too
+	board.FromFen("4k3/8/8/8/8/8/6p1/4K2R b - - 0 1")
+	if board.HasPieces(false) {
+		t.Errorf("black pawn counted as a piece")
+	}
+	if !board.MakeUCIMove("g2h1q") {
+		t.Fatalf("g2h1q is not legal")
+	}
+	if !board.HasPieces(false) || board.NonPawnPieces(false) != 1 {
+		t.Errorf("promoted queen not counted: %d pieces", board.NonPawnPieces(false))
+	}
+}
+
+func TestSearchOptions(t *testing.T) {
+	board := Chessboard{}
+	// Mate in 2: a rook cuts the king off on the 7th rank and the other mates on the 8th
+	board.FromFen("7k/8/8/8/8/8/8/RR4K1 w - - 0 1")
+
+	for _, options := range []SearchOptions{{}, {PVS: true}, {NullMove: true}, {LMR: true}, DefaultSearchOptions()} {
+		e := NewEngine()
+		e.Options = options
+		info := e.Think(&board, SearchLimits{Depth: 4}, nil)
+		if move := board.MoveUCI(info.Move); move != "a1a7" && move != "b1b7" || info.Mate != 2 {
+			t.Errorf("%+v: %s with mate %d, want a rook lift mating in 2", options, move, info.Mate)
+		}
+	}
+
+	// Zugzwang, passing is no threat so the null move can not see it: 1. Ra6 and 2. b7 mates.
+	// Black has two bishops, so the null move is searched, and the verification
+	// search of its moves keeps it from cutting the line
+	board.FromFen("kbK4b/pp4p1/1P4P1/8/8/8/8/R7 w - - 0 1")
+	if board.NonPawnPieces(false) < NULL_MOVE_MIN_PIECES {
+		t.Fatalf("black can not pass with %d pieces", board.NonPawnPieces(false))
+	}
+	for _, options := range []SearchOptions{{NullMove: true}, {NullMove: true, LMR: true}, DefaultSearchOptions()} {
+		e := NewEngine()
+		e.Options = options
+		info := e.Think(&board, SearchLimits{Depth: 4}, nil)
+		if move := board.MoveUCI(info.Move); move != "a1a6" || info.Mate != 2 {
+			t.Errorf("%+v: %s with mate %d, want a1a6 mating in 2", options, move, info.Mate)
+		}
+	}
+}
+
+func TestSearchOptionsAgree(t *testing.T) {
+	board := Chessboard{}
+	board.FromFen("r1bqkb1r/pppp1ppp/2n2n2/4p3/2B1P3/5N2/PPPP1PPP/RNBQK2R w KQkq - 4 4")
+
+	// Reductions may change the score but PVS alone only prunes
+	e := NewEngine()
+	e.Options = SearchOptions{}
+	states := 0
+	want, _ := e.Minimax(&board, 4, math.Inf(-1), math.Inf(+1), true, &states)
+
+	e = NewEngine()
+	e.Options = SearchOptions{PVS: true}
+	pvs_states := 0
+	if score, _ := e.Minimax(&board, 4, math.Inf(-1), math.Inf(+1), true, &pvs_states); score != want {
+		t.Errorf("PVS scored %.2f, want %.2f", score, want)
+	}
+}
+
+func TestPrincipalVariation(t *testing.T) {
+	board := Chessboard{}
+	board.FromFen("7k/8/8/8/8/8/8/RR4K1 w - - 0 1")
+
+	e := NewEngine()
+	info := e.Think(&board, SearchLimits{Depth: 4}, nil)
+	if len(info.PV) != 3 || board.MoveUCI(info.PV[0]) != board.MoveUCI(info.Move) {
+		t.Fatalf("pv %v, want the 3 plies of the mate starting with %s", board.LineUCI(info.PV), board.MoveUCI(info.Move))
+	}
+
+	// The line is legal and ends with the mate
+	line := board.LineSAN(info.PV)
+	for _, san := range line {
+		if err := board.MakeSANMove(san); err != nil {
+			t.Fatalf("pv %v: %v", line, err)
+		}
+	}
+	if team := board.ToMove(); len(board.PossibleMoves(team)) != 0 || board.VerifyState(team) {
+		t.Errorf("pv %v does not end with a checkmate", line)
+	}
+	if last := line[len(line)-1]; last[len(last)-1] != '#' {
+		t.Errorf("pv %v, want the last move to be a mate", line)
+	}
+}
+
+func TestMultiPV(t *testing.T) {
+	board := Chessboard{}
+	board.FromFen("r1bqkb1r/pppp1ppp/2n2n2/4p3/2B1P3/5N2/PPPP1PPP/RNBQK2R b KQkq - 5 4")
+	const depth = 3
+
+	e := NewEngine()
+	e.Options = SearchOptions{}
+	e.MultiPV = 4
+	info := e.Think(&board, SearchLimits{Depth: depth}, nil)
+	if len(info.Lines) != 4 || board.MoveUCI(info.Lines[0].Move) != board.MoveUCI(info.Move) {
+		t.Fatalf("%d lines, want 4 starting with the best move", len(info.Lines))
+	}
+
+	// Different moves, best first for black, each scored as if searched alone
+	plain := NewEngine()
+	plain.Options = SearchOptions{}
+	seen := map[string]bool{}
+	for i, line := range info.Lines {
+		move := board.MoveUCI(line.Move)
+		if seen[move] {
+			t.Errorf("line %d repeats %s", i, move)
+		}
+		seen[move] = true
+		if i > 0 && line.Score < info.Lines[i-1].Score {
+			t.Errorf("line %d scored %.2f after %.2f", i, line.Score, info.Lines[i-1].Score)
+		}
+		if len(line.PV) == 0 || board.MoveUCI(line.PV[0]) != move {
+			t.Errorf("line %d: pv %v does not start with %s", i, board.LineUCI(line.PV), move)
+		}
+
+		child := board.Duplicate()
+		child.MakeUnsafeMove(line.Move, false)
+		states := 1
+		if score, _ := plain.minimax(&child, depth-1, 1, math.Inf(-1), math.Inf(+1), true, &states); score != line.Score {
+			t.Errorf("line %d: %s scored %.2f, searched alone %.2f", i, move, line.Score, score)
+		}
+	}
+}
+
+// The stats of an iteration count the searches of all its lines, not only the last one
+func TestMultiPVStats(t *testing.T) {
+	board := Chessboard{}
+	board.FromFen("r1bqkb1r/pppp1ppp/2n2n2/4p3/2B1P3/5N2/PPPP1PPP/RNBQK2R b KQkq - 5 4")
+	const depth = 3
+
+	single := NewEngine()
+	single.Options = SearchOptions{}
+	one := single.Think(&board, SearchLimits{Depth: depth}, nil)
+
+	// Every root move is a line, the first one searched as the single line is
+	e := NewEngine()
+	e.Options = SearchOptions{}
+	e.MultiPV = len(board.PossibleMoves(false))
+	all := e.Think(&board, SearchLimits{Depth: depth}, nil)
+	if all.Stats.Cutoffs < one.Stats.Cutoffs {
+		t.Errorf("%d cutoffs with every line, %d with one", all.Stats.Cutoffs, one.Stats.Cutoffs)
+	}
+}
+
+func TestAspiration(t *testing.T) {
+	board := Chessboard{}
+	board.FromFen("r1bqkb1r/pppp1ppp/2n2n2/4p3/2B1P3/5N2/PPPP1PPP/RNBQK2R w KQkq - 4 4")
+	const depth = 4
+
+	e := NewEngine()
+	e.Options = SearchOptions{}
+	states := 0
+	want, _ := e.Minimax(&board, depth, math.Inf(-1), math.Inf(+1), true, &states)
+
+	// A window far from the score fails and is widened until the score is exact
+	for _, last := range []float64{want + 600, want - 600, want + 5} {
+		e := NewEngine()
+		e.Options = SearchOptions{Aspiration: true}
+		stats := AspirationStats{}
+		states := 0
+		if score, _ := e.aspiration(&board, depth, true, last, &stats, &states); score != want {
+			t.Errorf("window around %.0f: scored %.2f, want %.2f", last, score, want)
+		}
+		fails := stats.FailLows + stats.FailHighs
+		if last == want+5 && fails != 0 || last != want+5 && fails == 0 {
+			t.Errorf("window around %.0f: %+v", last, stats)
+		}
+		if last == want+600 && stats.FailLows == 0 || last == want-600 && stats.FailHighs == 0 {
+			t.Errorf("window around %.0f failed on the wrong side: %+v", last, stats)
+		}
+	}
+
+	// Iterations from ASPIRATION_MIN_DEPTH on use the window
+	e = NewEngine()
+	info := e.Think(&board, SearchLimits{Depth: ASPIRATION_MIN_DEPTH + 1}, nil)
+	if info.Aspiration.Searches < 2 {
+		t.Errorf("%+v, want the last 2 iterations searched with a window", info.Aspiration)
+	}
+}
+
+func TestExtensions(t *testing.T) {
+	// Mate in 4 with the rooks, every white move is a check
+	board := Chessboard{}
+	board.FromFen("8/8/8/7k/8/8/8/RR4K1 w - - 0 1")
+	const depth = 5
+
+	e := NewEngine()
+	e.Options = SearchOptions{}
+	if info := e.Think(&board, SearchLimits{Depth: depth}, nil); info.Mate != 0 {
+		t.Errorf("mate in %d found without extensions at depth %d", info.Mate, depth)
+	}
+	e = NewEngine()
+	e.Options = SearchOptions{CheckExtension: true}
+	if info := e.Think(&board, SearchLimits{Depth: depth}, nil); info.Mate != 4 {
+		t.Errorf("check extension: mate %d, want mate in 4", info.Mate)
+	}
+
+	// Checks that repeat positions do not hide the mate in 2 (Rh2 Ka7 Ra2#)
+	board.FromFen("k7/8/8/8/8/8/8/1R4KR w - - 0 1")
+	e = NewEngine()
+	if info := e.Think(&board, SearchLimits{Depth: depth}, nil); info.Mate != 2 {
+		t.Errorf("every option: mate %d, score %.2f, want mate in 2", info.Mate, info.Score)
+	}
+}
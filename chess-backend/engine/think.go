@@ -0,0 +1,209 @@
+package engine
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Deepest iteration of a search limited only by time
+const MAX_SEARCH_DEPTH = 64
+
+// Half width of the first aspiration window around the score of the last iteration
+const ASPIRATION_WINDOW = 25
+
+// Iterations before this depth search with the full window
+const ASPIRATION_MIN_DEPTH = 4
+
+// Times the window grows after a failed search, wider windows become infinite
+const ASPIRATION_WIDEN = 4
+const ASPIRATION_MAX_WINDOW = 1000
+
+// Aspiration window searches of the root
+type AspirationStats struct {
+	// Root searches with a window around the last score, re-searches included
+	Searches int
+	// Searches repeated with a wider window because the score fell below or above it
+	FailLows  int
+	FailHighs int
+}
+
+// Percentage of aspiration searches that had to be repeated
+func (s AspirationStats) ResearchRate() float64 {
+	if s.Searches == 0 {
+		return 0
+	}
+	return 100 * float64(s.FailLows+s.FailHighs) / float64(s.Searches)
+}
+
+// Result of an iteration of the search
+type SearchInfo struct {
+	Depth int
+	// Minimax score, white positive
+	Score float64
+	// Moves to mate, positive when white mates and 0 if no mate was found
+	Mate int
+	Move PossibleMove
+	// Line expected after the search, starting with Move
+	PV []PossibleMove
+
+	// States searched and time used since the search started
+	States  int
+	Elapsed time.Duration
+
+	// Cutoffs of the iteration
+	Stats SearchStats
+	// Aspiration windows since the search started
+	Aspiration AspirationStats
+
+	// Best root moves, the first one is Move. More than one with MultiPV
+	Lines []RootLine
+}
+
+// One of the best root moves of a search and the line it expects
+type RootLine struct {
+	Score float64
+	Mate  int
+	Move  PossibleMove
+	PV    []PossibleMove
+}
+
+/*
+	Searches the best move of the side to move with iterative deepening until
+	LIMITS are reached. REPORT (may be nil) is called after every finished
+	iteration. Returns the last finished iteration, an interrupted one is
+	discarded. The move is invalid only if there are no legal moves. With
+	MultiPV every iteration searches the root once per line, leaving out the
+	moves of the lines already found
+*/
+func (e *Engine) Think(c *Chessboard, limits SearchLimits, report func(SearchInfo)) SearchInfo {
+	start := time.Now()
+	team := c.toMove
+
+	moves := c.PossibleMoves(team)
+	best := SearchInfo{Move: PossibleMove{invalid: true}}
+	if len(moves) == 0 {
+		return best
+	}
+	// Played if not even the first iteration finishes
+	best.Move = moves[0]
+	best.PV = []PossibleMove{moves[0]}
+
+	// Nothing to think about
+	if len(moves) == 1 && limits.Timed() {
+		best.Score = c.Evaluate()
+		return best
+	}
+
+	max_depth := limits.Depth
+	if max_depth <= 0 {
+		max_depth = e.Depth
+		if limits.Timed() {
+			max_depth = MAX_SEARCH_DEPTH
+		}
+	}
+	if limits.Hard > 0 {
+		timer := time.AfterFunc(limits.Hard, e.Stop)
+		defer timer.Stop()
+	}
+
+	multipv := e.MultiPV
+	if multipv < 1 {
+		multipv = 1
+	}
+	if multipv > len(moves) {
+		multipv = len(moves)
+	}
+
+	states := 0
+	for depth := 1; depth <= max_depth; depth++ {
+		// Counted over every search of the iteration, all lines and re-searches
+		e.stats = SearchStats{}
+		lines := []RootLine{}
+		for len(lines) < multipv {
+			// Each line expects the score it had in the last iteration
+			last := math.Inf(+1)
+			if len(lines) < len(best.Lines) {
+				last = best.Lines[len(lines)].Score
+			}
+			score, pm := e.aspiration(c, depth, team, last, &best.Aspiration, &states)
+			if e.Stopped() {
+				break
+			}
+
+			line := RootLine{Score: score, Mate: MateIn(score), Move: pm, PV: e.PV()}
+			lines = append(lines, line)
+			e.excluded = append(e.excluded, line.Move)
+		}
+		e.excluded = nil
+		if e.Stopped() {
+			break
+		}
+
+		// The best line first for the side to move
+		sort.SliceStable(lines, func(i, j int) bool {
+			if team {
+				return lines[i].Score > lines[j].Score
+			}
+			return lines[i].Score < lines[j].Score
+		})
+		best.Lines = lines
+		best.Move = lines[0].Move
+		best.PV = lines[0].PV
+		best.Depth = depth
+		best.Score = lines[0].Score
+		best.Mate = lines[0].Mate
+		best.States = states
+		best.Elapsed = time.Since(start)
+		best.Stats = e.Stats()
+		if report != nil {
+			report(best)
+		}
+
+		if limits.Soft > 0 && best.Elapsed > limits.Soft {
+			break
+		}
+	}
+
+	best.States = states
+	best.Elapsed = time.Since(start)
+	return best
+}
+
+/*
+	Searches the root to DEPTH with a window around LAST, the score of the
+	previous iteration. A score outside of the window is only a bound, so the
+	root is searched again with the window widened on the side it failed
+*/
+func (e *Engine) aspiration(c *Chessboard, depth int, team bool, last float64, stats *AspirationStats, states *int) (float64, PossibleMove) {
+	if !e.Options.Aspiration || depth < ASPIRATION_MIN_DEPTH || math.IsInf(last, 0) || IsMate(last) {
+		return e.Minimax(c, depth, math.Inf(-1), math.Inf(+1), team, states)
+	}
+
+	delta := float64(ASPIRATION_WINDOW)
+	alfa, beta := last-delta, last+delta
+	for {
+		stats.Searches++
+		score, pm := e.Minimax(c, depth, alfa, beta, team, states)
+		if e.Stopped() {
+			return score, pm
+		}
+
+		delta *= ASPIRATION_WIDEN
+		if score <= alfa && !math.IsInf(alfa, -1) {
+			stats.FailLows++
+			alfa = score - delta
+			if delta > ASPIRATION_MAX_WINDOW {
+				alfa = math.Inf(-1)
+			}
+		} else if score >= beta && !math.IsInf(beta, +1) {
+			stats.FailHighs++
+			beta = score + delta
+			if delta > ASPIRATION_MAX_WINDOW {
+				beta = math.Inf(+1)
+			}
+		} else {
+			return score, pm
+		}
+	}
+}
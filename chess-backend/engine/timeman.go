@@ -0,0 +1,82 @@
+package engine
+
+import "time"
+
+// Time kept aside on every move for the answer to reach the other side
+const MOVE_OVERHEAD = 50 * time.Millisecond
+
+// Moves left in the game assumed when the clock has no moves to go
+const DEFAULT_MOVES_TO_GO = 30
+
+/*
+	Limits of one search. Zero values mean no limit, a search without any limit
+	goes to the engine Depth
+*/
+type SearchLimits struct {
+	// Deepest iteration
+	Depth int
+
+	// No new iteration starts after the soft limit, the running one is
+	// interrupted at the hard limit
+	Soft time.Duration
+	Hard time.Duration
+}
+
+// Returns TRUE if the search is limited by time
+func (l SearchLimits) Timed() bool {
+	return l.Soft > 0 || l.Hard > 0
+}
+
+// Limits of a search that must answer in D
+func MoveTime(d time.Duration) SearchLimits {
+	return SearchLimits{Soft: d / 2, Hard: d}
+}
+
+// Clock of one side: time left, increment per move and moves to the next time control (0 for the whole game)
+type Clock struct {
+	Remaining time.Duration
+	Increment time.Duration
+	MovesToGo int
+}
+
+/*
+	Limits of the next move. The target is an even share of the time left plus
+	most of the increment. An iteration is about as long as all the previous
+	ones, so none starts after half the target, and the running one may use up
+	to twice the target but never more than half of the clock
+*/
+func (k Clock) Limits() SearchLimits {
+	if k.Remaining <= 0 {
+		return SearchLimits{}
+	}
+	left := k.Remaining - MOVE_OVERHEAD
+	if left <= 0 {
+		left = k.Remaining / 2
+	}
+
+	moves_to_go := k.MovesToGo
+	if moves_to_go <= 0 {
+		moves_to_go = DEFAULT_MOVES_TO_GO
+	}
+	target := left/time.Duration(moves_to_go) + k.Increment*3/4
+
+	hard := 2 * target
+	if hard > left/2 {
+		hard = left / 2
+	}
+	if target > hard {
+		target = hard
+	}
+	return SearchLimits{Soft: target / 2, Hard: hard}
+}
+
+// Charges a move that took USED to the clock
+func (k *Clock) Spend(used time.Duration) {
+	if k.Remaining <= 0 {
+		return
+	}
+	k.Remaining += k.Increment - used
+	if k.MovesToGo > 0 {
+		k.MovesToGo--
+	}
+}
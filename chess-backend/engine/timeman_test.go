@@ -0,0 +1,111 @@
+package engine
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClockLimits(t *testing.T) {
+	if limits := (Clock{}).Limits(); limits.Timed() {
+		t.Errorf("no clock gave the limits %+v", limits)
+	}
+
+	for _, clock := range []Clock{
+		{Remaining: 5 * time.Minute},
+		{Remaining: 5 * time.Minute, Increment: 3 * time.Second},
+		{Remaining: 90 * time.Second, Increment: 2 * time.Second, MovesToGo: 1},
+		{Remaining: 2 * time.Second, Increment: 10 * time.Second},
+		{Remaining: 20 * time.Millisecond},
+	} {
+		limits := clock.Limits()
+		if limits.Soft <= 0 || limits.Soft > limits.Hard {
+			t.Errorf("%+v: soft limit %s, hard limit %s", clock, limits.Soft, limits.Hard)
+		}
+		if limits.Hard > clock.Remaining/2 {
+			t.Errorf("%+v: hard limit %s uses more than half the clock", clock, limits.Hard)
+		}
+	}
+
+	// Fewer moves to go leave more time for each
+	few := Clock{Remaining: time.Minute, MovesToGo: 5}.Limits()
+	many := Clock{Remaining: time.Minute, MovesToGo: 40}.Limits()
+	if few.Soft <= many.Soft {
+		t.Errorf("5 moves to go: %s, 40 moves to go: %s", few.Soft, many.Soft)
+	}
+}
+
+func TestClockSpend(t *testing.T) {
+	clock := Clock{Remaining: time.Minute, Increment: 2 * time.Second, MovesToGo: 10}
+	clock.Spend(5 * time.Second)
+	if clock.Remaining != 57*time.Second || clock.MovesToGo != 9 {
+		t.Errorf("clock after a 5s move = %+v, want 57s and 9 moves to go", clock)
+	}
+}
+
+func TestThinkDepth(t *testing.T) {
+	board := Chessboard{}
+	board.FromFen("6k1/5ppp/8/8/8/8/5PPP/3R2K1 w - - 0 1")
+
+	depths := []int{}
+	info := NewEngine().Think(&board, SearchLimits{Depth: 3}, func(info SearchInfo) {
+		depths = append(depths, info.Depth)
+	})
+	if len(depths) != 3 || depths[2] != 3 {
+		t.Errorf("iterations %v, want 1 2 3", depths)
+	}
+	if move := board.MoveUCI(info.Move); info.Depth != 3 || move != "d1d8" {
+		t.Errorf("depth %d move %s, want depth 3 d1d8", info.Depth, move)
+	}
+}
+
+func TestThinkRepeatedRoot(t *testing.T) {
+	board := Chessboard{}
+	board.FromFen(START_FEN)
+	for _, move := range []string{"g1f3", "g8f6", "f3g1", "f6g8"} {
+		board.MakeUCIMove(move)
+	}
+
+	// The root is searched every iteration, even when the position was seen
+	// before or searched already, and finds the move of a fresh search
+	e := NewEngine()
+	e.Deterministic = true
+	moves := []string{}
+	for search := 0; search < 2; search++ {
+		last := 0
+		e.Think(&board, SearchLimits{Depth: 4}, func(info SearchInfo) {
+			move := board.MoveUCI(info.Move)
+			if search == 0 {
+				moves = append(moves, move)
+			} else if move != moves[info.Depth-1] {
+				t.Errorf("search again, depth %d: move %s, want %s", info.Depth, move, moves[info.Depth-1])
+			}
+			if info.States == last || info.Score == 0.01 || len(info.PV) == 0 {
+				t.Errorf("search %d depth %d: %d states, score %.2f, pv %v", search, info.Depth, info.States-last, info.Score, board.LineUCI(info.PV))
+			}
+			last = info.States
+		})
+	}
+}
+
+func TestThinkTimeLimits(t *testing.T) {
+	board := Chessboard{}
+	board.FromFen("r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+
+	e := NewEngine()
+	start := time.Now()
+	info := e.Think(&board, MoveTime(200*time.Millisecond), nil)
+	if elapsed := time.Since(start); elapsed > 400*time.Millisecond {
+		t.Errorf("search took %s with a 200ms limit", elapsed)
+	}
+	if info.Move.Invalid() || info.Depth < 1 {
+		t.Errorf("no move from the last finished iteration: %+v", info)
+	}
+
+	// The only legal move is played without searching
+	board.FromFen("7k/8/8/8/8/8/6q1/7K w - - 0 1")
+	e.ClearStop()
+	info = e.Think(&board, MoveTime(time.Minute), nil)
+	if move := board.MoveUCI(info.Move); move != "h1g2" || info.Depth != 0 {
+		t.Errorf("move %s at depth %d, want h1g2 without a search", move, info.Depth)
+	}
+}
@@ -0,0 +1,276 @@
+package engine
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"log"
+	"math"
+	"os"
+	"strings"
+	"sync/atomic"
+	"unsafe"
+)
+
+// Transposition table size (MB) of a new engine
+const TT_DEFAULT_MB = 16
+
+// Kind of score held by a transposition entry
+const (
+	TT_EXACT = iota + 1
+	TT_LOWER // The score is a lower bound (the search failed high)
+	TT_UPPER // The score is an upper bound (the search failed low)
+)
+
+// Transposition Table entry
+type TranspositionEntry struct {
+	key   int64
+	score float64
+	depth int16
+	flag  uint8
+	age   uint8
+
+	// Best move found (piece -1 for none)
+	piece      int8
+	end_pos    uint8
+	promote_to byte
+}
+
+// Returns TRUE if the entry holds BEST_MOVE
+func (t *TranspositionEntry) isMove(pm PossibleMove) bool {
+	return t.piece >= 0 && int(t.piece) == pm.piece && t.end_pos == pm.end_pos.toByte() && (!pm.promote || t.promote_to == pm.promote_to)
+}
+
+// Promotion pieces as stored in a packed entry (0 is no promotion)
+const ttPromotions = " qrbn"
+
+/*
+	Packs the entry (but its key) in a word:
+	score (float32) | depth (8 bits) | flag (2) | age (8) | piece (5) | square (6) | promotion (3)
+*/
+func (t *TranspositionEntry) pack() uint64 {
+	piece := uint64(31)
+	if t.piece >= 0 {
+		piece = uint64(t.piece)
+	}
+	promote := uint64(0)
+	if i := strings.IndexByte(ttPromotions, t.promote_to); i > 0 {
+		promote = uint64(i)
+	}
+	return uint64(math.Float32bits(float32(t.score))) |
+		uint64(uint8(t.depth))<<32 |
+		uint64(t.flag&0b11)<<40 |
+		uint64(t.age)<<42 |
+		piece<<50 |
+		uint64(t.end_pos&0b111111)<<55 |
+		promote<<61
+}
+
+// Unpacks the entry of KEY from DATA
+func unpackEntry(key int64, data uint64) TranspositionEntry {
+	entry := TranspositionEntry{
+		key:     key,
+		score:   float64(math.Float32frombits(uint32(data))),
+		depth:   int16(int8(data >> 32)),
+		flag:    uint8(data>>40) & 0b11,
+		age:     uint8(data >> 42),
+		piece:   int8(data>>50) & 0b11111,
+		end_pos: (1 << 7) | uint8(data>>55)&0b111111,
+	}
+	if entry.piece == 31 {
+		entry.piece, entry.end_pos = -1, 0
+	}
+	if promote := data >> 61; promote != 0 {
+		entry.promote_to = ttPromotions[promote]
+	}
+	return entry
+}
+
+/*
+	Table slot. Both words are read and written atomically, and the key is stored
+	xored with the data so that a slot torn by two threads writing it at once
+	does not match any position
+*/
+type ttSlot struct {
+	check uint64
+	data  uint64
+}
+
+// Reads the slot, returns FALSE if it is empty
+func (s *ttSlot) load() (TranspositionEntry, bool) {
+	data := atomic.LoadUint64(&s.data)
+	check := atomic.LoadUint64(&s.check)
+	if data == 0 {
+		return TranspositionEntry{}, false
+	}
+	return unpackEntry(int64(check^data), data), true
+}
+
+// Writes ENTRY to the slot
+func (s *ttSlot) save(entry TranspositionEntry) {
+	data := entry.pack()
+	atomic.StoreUint64(&s.data, data)
+	atomic.StoreUint64(&s.check, uint64(entry.key)^data)
+}
+
+/*
+	Fixed size transposition table. Entries are kept in buckets of two: a new
+	entry replaces the one with the same position, an empty one, one left by an
+	older search or the shallower one, in that order. The table takes no locks,
+	several threads may search with it at once
+*/
+type TranspositionTable struct {
+	slots []ttSlot
+	mask  uint64
+
+	// Incremented on every new search, entries of older searches are replaced first
+	age uint8
+}
+
+// Creates a table using at most MB megabytes
+func NewTranspositionTable(mb int) *TranspositionTable {
+	if mb < 1 {
+		mb = 1
+	}
+	size := uint64(mb) * 1024 * 1024 / uint64(unsafe.Sizeof(ttSlot{}))
+
+	// Round down to a power of two so that the index is a mask of the key
+	buckets := uint64(1)
+	for buckets*4 <= size {
+		buckets *= 2
+	}
+	return &TranspositionTable{slots: make([]ttSlot, buckets*2), mask: buckets - 1}
+}
+
+// Removes every entry
+func (t *TranspositionTable) Clear() {
+	for i := range t.slots {
+		t.slots[i] = ttSlot{}
+	}
+	t.age = 0
+}
+
+// Starts a new search, making the current entries replaceable
+func (t *TranspositionTable) newSearch() {
+	t.age++
+}
+
+// Returns the bucket of KEY
+func (t *TranspositionTable) bucket(key int64) []ttSlot {
+	idx := (uint64(key) & t.mask) * 2
+	return t.slots[idx : idx+2]
+}
+
+// Finds the entry of KEY
+func (t *TranspositionTable) probe(key int64) (TranspositionEntry, bool) {
+	bucket := t.bucket(key)
+	for i := range bucket {
+		if entry, ok := bucket[i].load(); ok && entry.key == key {
+			return entry, true
+		}
+	}
+	return TranspositionEntry{piece: -1}, false
+}
+
+// Stores the result of a search of KEY to DEPTH
+func (t *TranspositionTable) store(key int64, score float64, depth int, flag uint8, best PossibleMove) {
+	entry := TranspositionEntry{key: key, score: score, depth: int16(depth), flag: flag, age: t.age, piece: -1}
+	if !best.invalid {
+		entry.piece = int8(best.piece)
+		entry.end_pos = best.end_pos.toByte()
+		entry.promote_to = best.promote_to
+	}
+	t.insert(entry)
+}
+
+// Writes ENTRY over the most replaceable entry of its bucket
+func (t *TranspositionTable) insert(entry TranspositionEntry) {
+	bucket := t.bucket(entry.key)
+	victim := -1
+	var victim_entry TranspositionEntry
+	for i := range bucket {
+		old, ok := bucket[i].load()
+		if !ok || old.key == entry.key {
+			// Keep the best move of a previous search of the position
+			if ok && entry.piece < 0 {
+				entry.piece, entry.end_pos, entry.promote_to = old.piece, old.end_pos, old.promote_to
+			}
+			victim = i
+			break
+		}
+		if victim < 0 || t.replaceable(old, victim_entry) {
+			victim = i
+			victim_entry = old
+		}
+	}
+	bucket[victim].save(entry)
+}
+
+// Returns TRUE if A should be replaced before B
+func (t *TranspositionTable) replaceable(a TranspositionEntry, b TranspositionEntry) bool {
+	a_old := a.age != t.age
+	b_old := b.age != t.age
+	if a_old != b_old {
+		return a_old
+	}
+	return a.depth < b.depth
+}
+
+// Transposition entry as written to a file
+type ttRecord struct {
+	Key       int64
+	Score     float64
+	Depth     int16
+	Flag      uint8
+	Piece     int8
+	EndPos    uint8
+	PromoteTo uint8
+}
+
+// Save transposition data to memory
+func (e *Engine) SaveTranspositionTable(filename string) {
+	f, err := os.Create(filename)
+	if err != nil {
+		log.Panic(err)
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	defer w.Flush()
+	for i := range e.transposition_table.slots {
+		entry, ok := e.transposition_table.slots[i].load()
+		if !ok {
+			continue
+		}
+		binary.Write(w, binary.LittleEndian, ttRecord{
+			Key: entry.key, Score: entry.score, Depth: entry.depth, Flag: entry.flag,
+			Piece: entry.piece, EndPos: entry.end_pos, PromoteTo: entry.promote_to,
+		})
+	}
+}
+
+// Loads transposition table from file
+func (e *Engine) LoadTranspositionTable(filename string) {
+	f, err := os.Open(filename)
+	if err != nil {
+		log.Println("Could not open file")
+		return
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	t := e.transposition_table
+	for {
+		var record ttRecord
+		if err := binary.Read(r, binary.LittleEndian, &record); err != nil {
+			if err != io.EOF {
+				log.Println("Could not read transposition table:", err)
+			}
+			return
+		}
+		t.insert(TranspositionEntry{
+			key: record.Key, score: record.Score, depth: record.Depth, flag: record.Flag, age: t.age,
+			piece: record.Piece, end_pos: record.EndPos, promote_to: record.PromoteTo,
+		})
+	}
+}
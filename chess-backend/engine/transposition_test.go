@@ -0,0 +1,156 @@
+package engine
+
+import (
+	"math"
+	"testing"
+	"unsafe"
+)
+
+func TestTranspositionTableSize(t *testing.T) {
+	for _, mb := range []int{1, 3, 16} {
+		table := NewTranspositionTable(mb)
+		size := len(table.slots) * int(unsafe.Sizeof(ttSlot{}))
+		if size > mb*1024*1024 || size <= mb*1024*1024/4 {
+			t.Errorf("%d MB table uses %d bytes", mb, size)
+		}
+	}
+}
+
+func TestTranspositionStoreProbe(t *testing.T) {
+	board := Chessboard{}
+	board.FromFen(START_FEN)
+	move := board.PossibleMoves(true)[3]
+
+	table := NewTranspositionTable(1)
+	if _, ok := table.probe(board.Hash()); ok {
+		t.Fatalf("empty table has an entry")
+	}
+
+	table.store(board.Hash(), 1.5, 4, TT_LOWER, move)
+	entry, ok := table.probe(board.Hash())
+	if !ok {
+		t.Fatalf("stored entry not found")
+	}
+	if entry.score != 1.5 || entry.depth != 4 || entry.flag != TT_LOWER || !entry.isMove(move) {
+		t.Errorf("probe = %+v, want score 1.5 depth 4 lower bound and the stored move", entry)
+	}
+
+	// A result without a move keeps the move of the previous search
+	table.store(board.Hash(), 2, 5, TT_UPPER, PossibleMove{invalid: true})
+	if entry, _ = table.probe(board.Hash()); entry.flag != TT_UPPER || !entry.isMove(move) {
+		t.Errorf("probe = %+v, want upper bound with the previous move", entry)
+	}
+
+	table.Clear()
+	if _, ok := table.probe(board.Hash()); ok {
+		t.Errorf("entry found after Clear")
+	}
+}
+
+func TestTranspositionReplacement(t *testing.T) {
+	table := NewTranspositionTable(1)
+	none := PossibleMove{invalid: true}
+
+	// Keys sharing the same bucket
+	step := int64(table.mask + 1)
+	deep, shallow, other := int64(7), 7+step, 7+2*step
+
+	table.store(deep, 1, 6, TT_EXACT, none)
+	table.store(shallow, 2, 2, TT_EXACT, none)
+	table.store(other, 3, 4, TT_EXACT, none)
+	if _, ok := table.probe(shallow); ok {
+		t.Errorf("shallower entry was kept")
+	}
+	if _, ok := table.probe(deep); !ok {
+		t.Errorf("deeper entry was replaced")
+	}
+
+	// Entries of older searches go first, whatever their depth
+	table.newSearch()
+	table.store(other, 3, 4, TT_EXACT, none)
+	table.store(shallow, 2, 1, TT_EXACT, none)
+	if _, ok := table.probe(deep); ok {
+		t.Errorf("entry of an older search was kept")
+	}
+	if _, ok := table.probe(other); !ok {
+		t.Errorf("entry of the current search was replaced")
+	}
+}
+
+func TestSearchWithTransposition(t *testing.T) {
+	board := Chessboard{}
+	board.FromFen("6k1/5ppp/8/8/8/8/5PPP/3R2K1 w - - 0 1")
+
+	// The table filled by each iteration must not change the result of the next ones
+	e := NewEngine()
+	for depth := 2; depth <= 4; depth++ {
+		states := 0
+		score, pm := e.Minimax(&board, depth, math.Inf(-1), math.Inf(+1), true, &states)
+		if move := board.MoveUCI(pm); move != "d1d8" {
+			t.Errorf("depth %d: best move %s, want d1d8", depth, move)
+		}
+		if want := float64(MATE_SCORE - 1); score != want {
+			t.Errorf("depth %d: score %.0f, want %.0f", depth, score, want)
+		}
+	}
+
+	// A new game starts with an empty table
+	e.NewGame()
+	if _, ok := e.transposition_table.probe(board.Hash()); ok {
+		t.Errorf("table not cleared by NewGame")
+	}
+}
+
+func TestTranspositionEntryPacking(t *testing.T) {
+	for _, entry := range []TranspositionEntry{
+		{key: -42, score: -300000, depth: 64, flag: TT_EXACT, age: 255, piece: -1},
+		{key: 1 << 62, score: 12.25, depth: 1, flag: TT_UPPER, age: 3, piece: 23, end_pos: 0b10111000, promote_to: 'n'},
+		{key: 7, score: 0, depth: 0, flag: TT_LOWER, piece: 0, end_pos: 0b10000000},
+	} {
+		if got := unpackEntry(entry.key, entry.pack()); got != entry {
+			t.Errorf("unpack(pack(%+v)) = %+v", entry, got)
+		}
+	}
+}
+
+func TestParallelSearch(t *testing.T) {
+	board := Chessboard{}
+	board.FromFen("6k1/5ppp/8/8/8/8/5PPP/3R2K1 w - - 0 1")
+	fen := board.Fen()
+
+	e := NewEngine()
+	e.Threads = 4
+	states := 0
+	score, pm := e.Minimax(&board, 4, math.Inf(-1), math.Inf(+1), true, &states)
+	if move := board.MoveUCI(pm); move != "d1d8" || score != MATE_SCORE-1 {
+		t.Errorf("best move %s with score %.0f, want d1d8 with %d", move, score, MATE_SCORE-1)
+	}
+	if board.Fen() != fen {
+		t.Errorf("board changed by the search: %s, want %s", board.Fen(), fen)
+	}
+
+}
+
+// Saved entries load back as they were, mates included
+func TestTranspositionTableFile(t *testing.T) {
+	board := Chessboard{}
+	board.FromFen(START_FEN)
+	move := board.PossibleMoves(true)[3]
+
+	e := NewEngine()
+	e.SetHashSize(1)
+	e.transposition_table.store(board.Hash(), MATE_SCORE-3, 6, TT_EXACT, move)
+	filename := t.TempDir() + "/table"
+	e.SaveTranspositionTable(filename)
+
+	loaded := NewEngine()
+	loaded.SetHashSize(1)
+	loaded.LoadTranspositionTable(filename)
+	entry, ok := loaded.transposition_table.probe(board.Hash())
+	if !ok {
+		t.Fatalf("saved entry not loaded")
+	}
+	if entry.score != MATE_SCORE-3 || entry.depth != 6 || entry.flag != TT_EXACT || !entry.isMove(move) {
+		t.Errorf("loaded %+v, want the mate in 3 of depth 6 and its move", entry)
+	}
+}
@@ -0,0 +1,99 @@
+package engine
+
+import "math/rand"
+
+// Zobrist hash table data
+type zobristTable struct {
+	zhtable          [64][18]int64
+	zh_black_to_move int64
+	zh_castling      [4]int64
+	zh_enpassant     [8]int64
+}
+
+// Keys shared by every board, so that hashes can be compared between engines
+var zobrist zobristTable
+
+func init() {
+	zobrist.init_zhtable()
+}
+
+// Init the Zobrist hash table used for hasing
+func (z *zobristTable) init_zhtable() {
+	r := rand.New(rand.NewSource(190520024679183427))
+
+	for idx := range z.zhtable {
+		for idx2 := range z.zhtable[idx] {
+			z.zhtable[idx][idx2] = r.Int63()
+		}
+	}
+	z.zh_black_to_move = r.Int63()
+
+	for idx := range z.zh_castling {
+		z.zh_castling[idx] = r.Int63()
+	}
+	for idx := range z.zh_enpassant {
+		z.zh_enpassant[idx] = r.Int63()
+	}
+}
+
+// Key of a piece of type NAME (r, n, b, q, k or p) from TEAM on the square POS
+func (z *zobristTable) pieceKey(name string, team bool, pos Piece) int64 {
+	index := pieceIndexMap[name[0]][0]
+	if !team {
+		index += 8
+	}
+	return z.zhtable[(pos>>3)&0b111+(pos&0b111*8)][index]
+}
+
+// Key of the castling rights
+func (z *zobristTable) castlingKey(wK bool, wQ bool, bK bool, bQ bool) int64 {
+	var h int64
+	for idx, right := range [4]bool{wK, wQ, bK, bQ} {
+		if right {
+			h ^= z.zh_castling[idx]
+		}
+	}
+	return h
+}
+
+// Key of the en passant square EP (0 for none)
+func (z *zobristTable) enpassantKey(ep uint8) int64 {
+	if ep == 0 {
+		return 0
+	}
+	return z.zh_enpassant[(ep>>3)&0b111]
+}
+
+/*
+	Hashes a Board with TEAM to move into an int64. The en passant file is only
+	hashed when the capture can be made, so equal hashes mean equal positions
+*/
+func (c *Chessboard) zobristHash(team bool) int64 {
+	var h int64
+	if !team {
+		h = h ^ zobrist.zh_black_to_move
+	}
+	for idx, pos := range c.white {
+		if pos != 0 {
+			h = h ^ zobrist.pieceKey(c.PieceName(idx, true), true, pos)
+		}
+	}
+	for idx, pos := range c.black {
+		if pos != 0 {
+			h = h ^ zobrist.pieceKey(c.PieceName(idx, false), false, pos)
+		}
+	}
+	h = h ^ zobrist.castlingKey(c.wK, c.wQ, c.bK, c.bQ)
+	return h ^ zobrist.enpassantKey(c.enpassantCapture(team))
+}
+
+// Recalculates the hash of the board from scratch
+func (c *Chessboard) rehash() {
+	c.hash = c.zobristHash(c.toMove)
+	c.hash_ep = c.enpassantCapture(c.toMove)
+}
+
+// Returns the Zobrist hash of the position
+func (c *Chessboard) Hash() int64 {
+	return c.hash
+}
@@ -0,0 +1,78 @@
+package engine
+
+import "testing"
+
+// Walks every line to DEPTH checking the incremental hash against a full recalculation
+func checkIncrementalHash(t *testing.T, c *Chessboard, depth int, line string) {
+	if depth == 0 {
+		return
+	}
+	var board Chessboard
+	for _, pm := range c.PossibleMoves(c.toMove) {
+		move := line + " " + c.MoveUCI(pm)
+		c.SaveState(&board)
+		board.MakeUnsafeMove(pm, c.toMove)
+		if want := board.zobristHash(board.toMove); board.Hash() != want {
+			t.Fatalf("hash after%s = %x, want %x", move, board.Hash(), want)
+		}
+		if want := board.enpassantCapture(board.toMove); board.hash_ep != want {
+			t.Fatalf("hashed en passant square after%s = %d, want %d", move, board.hash_ep, want)
+		}
+		checkIncrementalHash(t, &board, depth-1, move)
+	}
+}
+
+func TestIncrementalHash(t *testing.T) {
+	for _, tc := range perftCases {
+		if testing.Short() && tc.depth > 4 {
+			continue
+		}
+		board := Chessboard{}
+		board.FromFen(tc.fen)
+		checkIncrementalHash(t, &board, 3, tc.name+":")
+	}
+}
+
+func TestHashTranspositions(t *testing.T) {
+	a := Chessboard{}
+	a.FromFen(START_FEN)
+	b := Chessboard{}
+	b.FromFen(START_FEN)
+	for _, move := range []string{"g1f3", "g8f6", "b1c3"} {
+		a.MakeUCIMove(move)
+	}
+	for _, move := range []string{"b1c3", "g8f6", "g1f3"} {
+		b.MakeUCIMove(move)
+	}
+	if a.Hash() != b.Hash() {
+		t.Errorf("same position reached by another move order has a different hash")
+	}
+
+	// The same squares with another side to move, castling rights or en passant capture are different positions
+	for _, pair := range [][2]string{
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "r3k2r/8/8/8/8/8/8/R3K2R w Kkq - 0 1"},
+		{"4k3/8/8/8/3pP3/8/8/4K3 b - e3 0 1", "4k3/8/8/8/3pP3/8/8/4K3 b - - 0 1"},
+	} {
+		a.FromFen(pair[0])
+		b.FromFen(pair[1])
+		if a.Hash() == b.Hash() {
+			t.Errorf("%s and %s have the same hash", pair[0], pair[1])
+		}
+	}
+}
+
+func TestMakeMoveHash(t *testing.T) {
+	board := Chessboard{}
+	board.FromFen("r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+	for _, move := range []string{"a2a4", "b4a3", "e1c1", "e8g8", "d5e6", "a3b2", "c1b1", "h3g2", "e6f7", "g8h8", "b1b2", "g2h1q"} {
+		if !board.MakeUCIMove(move) {
+			t.Fatalf("%s is not legal in %s", move, board.Fen())
+		}
+		loaded := Chessboard{}
+		loaded.FromFen(board.Fen())
+		if board.Hash() != loaded.Hash() {
+			t.Errorf("hash after %s differs from the hash of %s", move, board.Fen())
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+	"yrk06/chess-backend/engine"
+)
+
+var gamesDir = flag.String("games", "games", "directory where the games played on the server are saved as PGN")
+
+// Name of the engine in the PGN player tags
+const BOT_NAME = "eXtreme Go Chess"
+
+// Number of games started since the server came up, keeps file names unique
+var games_started int64
+
+// A game played through the websocket server. Saved as PGN after every move
+type gameRecord struct {
+	game *engine.Game
+	path string
+}
+
+// Starts recording a game played through ROUTE from FEN
+func new_game_record(route string, fen string) *gameRecord {
+	now := time.Now()
+	game := engine.NewPGNGame(fen)
+	game.Tags["Event"] = "Casual game (/" + route + ")"
+	game.Tags["Site"] = *addr
+	game.Tags["Date"] = now.Format("2006.01.02")
+	game.Tags["Round"] = "-"
+
+	name := fmt.Sprintf("%s-%s-%d.pgn", now.Format("20060102-150405"), route, atomic.AddInt64(&games_started, 1))
+	return &gameRecord{game: game, path: filepath.Join(*gamesDir, name)}
+}
+
+// Sets the names of the players
+func (r *gameRecord) players(white string, black string) {
+	r.game.Tags["White"] = white
+	r.game.Tags["Black"] = black
+	r.save()
+}
+
+// Records a move in SAN with an optional comment
+func (r *gameRecord) move(san string, comment string) {
+	r.game.AddMove(san, comment)
+	r.save()
+}
+
+// Records the result of the game, LOSER is the team that was mated. Use finish_draw for draws
+func (r *gameRecord) finish(loser bool) {
+	r.game.SetResult(map[bool]string{true: "0-1", false: "1-0"}[loser])
+	r.save()
+}
+
+// Records a drawn game
+func (r *gameRecord) finish_draw() {
+	r.game.SetResult("1/2-1/2")
+	r.save()
+}
+
+// Writes the game to the games directory
+func (r *gameRecord) save() {
+	if err := os.MkdirAll(*gamesDir, 0755); err != nil {
+		log.Println("save game:", err)
+		return
+	}
+	if err := os.WriteFile(r.path, []byte(r.game.PGN()), 0644); err != nil {
+		log.Println("save game:", err)
+	}
+}
+
+// PGN comment for a bot move of TEAM found by the search INFO
+func bot_comment(info engine.SearchInfo, team bool) string {
+	if moves, mate := mateIn(info.Mate, team); mate {
+		return fmt.Sprintf("depth %d, mate %d", info.Depth, moves)
+	}
+	return fmt.Sprintf("depth %d, eval %+.2f", info.Depth, math.Round(info.Score)/100)
+}
+
+// Serves the saved games under /games/
+func handle_games() {
+	mime.AddExtensionType(".pgn", "application/x-chess-pgn")
+	if err := os.MkdirAll(*gamesDir, 0755); err != nil {
+		log.Println("games directory:", err)
+	}
+	http.Handle("/games/", http.StripPrefix("/games/", http.FileServer(http.Dir(*gamesDir))))
+}
@@ -2,7 +2,6 @@ module yrk06/chess-backend
 
 go 1.18
 
-require (
-	github.com/gorilla/websocket v1.5.0 // indirect
-	github.com/webview/webview v0.0.0-20220509021302-b158f065f118 // indirect
-)
+require github.com/gorilla/websocket v1.5.0
+
+require github.com/webview/webview v0.0.0-20220509021302-b158f065f118 // indirect
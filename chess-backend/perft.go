@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+	"yrk06/chess-backend/engine"
+)
+
+// Handles "perft [divide] DEPTH" on the -startpos position
+func run_perft(args []string) {
+	divide := len(args) > 0 && args[0] == "divide"
+	if divide {
+		args = args[1:]
+	}
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "usage: perft [divide] DEPTH")
+		os.Exit(2)
+	}
+	depth, err := strconv.Atoi(args[0])
+	if err != nil || depth < 0 {
+		fmt.Fprintf(os.Stderr, "invalid perft depth %q\n", args[0])
+		os.Exit(2)
+	}
+
+	board := engine.Chessboard{}
+	board.FromFen(*startpos)
+
+	start := time.Now()
+	nodes := 0
+	if divide {
+		counts := board.Divide(depth)
+		moves := make([]string, 0, len(counts))
+		for move := range counts {
+			moves = append(moves, move)
+		}
+		sort.Strings(moves)
+
+		for _, move := range moves {
+			fmt.Printf("%s: %d\n", move, counts[move])
+			nodes += counts[move]
+		}
+		fmt.Println()
+	} else {
+		nodes = board.Perft(depth)
+	}
+	d := time.Since(start)
+
+	fmt.Printf("Nodes searched: %d\n", nodes)
+	log.Printf("Perft %d, %fs, Mean %f nodes/second", depth, d.Seconds(), float64(nodes)/d.Seconds())
+}
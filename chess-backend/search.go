@@ -0,0 +1,18 @@
+package main
+
+import (
+	"yrk06/chess-backend/engine"
+)
+
+const START_FEN = engine.START_FEN
+
+// Moves to MATE (white positive) found by a search for TEAM. Negative when TEAM is getting mated
+func mateIn(mate int, team bool) (int, bool) {
+	if mate == 0 {
+		return 0, false
+	}
+	if !team {
+		return -mate, true
+	}
+	return mate, true
+}
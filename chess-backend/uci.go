@@ -0,0 +1,298 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"yrk06/chess-backend/engine"
+)
+
+var uciMode = flag.Bool("uci", false, "speak the UCI protocol over stdin/stdout instead of starting the server")
+
+// Search limits received in a "go" command
+type uciLimits struct {
+	depth     int
+	movetime  time.Duration
+	wtime     time.Duration
+	btime     time.Duration
+	winc      time.Duration
+	binc      time.Duration
+	movestogo int
+	infinite  bool
+}
+
+// UCI session over a reader and a writer
+type uciSession struct {
+	out   io.Writer
+	outMu sync.Mutex
+
+	board engine.Chessboard
+
+	searching sync.WaitGroup
+	// Closed by "stop" to release an infinite search
+	release chan struct{}
+}
+
+// Writes a line to the GUI
+func (u *uciSession) send(format string, a ...interface{}) {
+	u.outMu.Lock()
+	defer u.outMu.Unlock()
+	fmt.Fprintf(u.out, format+"\n", a...)
+}
+
+// Interrupts the running search (if any) and waits for its bestmove
+func (u *uciSession) stopSearch() {
+	bot.Stop()
+	if u.release != nil {
+		close(u.release)
+		u.release = nil
+	}
+	u.searching.Wait()
+}
+
+// Runs the UCI loop until "quit" or the end of the input
+func start_uci(in io.Reader, out io.Writer) {
+	u := &uciSession{out: out}
+	u.board.FromFen(START_FEN)
+
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch fields[0] {
+		case "uci":
+			u.send("id name eXtreme Go Chess")
+			u.send("id author yrk06")
+			u.send("option name Hash type spin default %d min 1 max 4096", engine.TT_DEFAULT_MB)
+			u.send("option name Threads type spin default %d min 1 max 256", engine.BOT_THREADS)
+			u.send("option name PVS type check default true")
+			u.send("option name NullMove type check default true")
+			u.send("option name LMR type check default true")
+			u.send("option name Aspiration type check default true")
+			u.send("option name CheckExtension type check default true")
+			u.send("option name PawnExtension type check default true")
+			u.send("option name SingularExtension type check default true")
+			u.send("option name Variety type spin default %d min 0 max 1000", engine.BOT_VARIETY)
+			u.send("option name Deterministic type check default false")
+			u.send("option name MultiPV type spin default 1 min 1 max 256")
+			u.send("uciok")
+		case "isready":
+			u.send("readyok")
+		case "ucinewgame":
+			u.stopSearch()
+			bot.NewGame()
+			u.board.FromFen(START_FEN)
+		case "setoption":
+			u.stopSearch()
+			u.setOption(fields[1:])
+		case "position":
+			u.stopSearch()
+			u.position(fields[1:])
+		case "go":
+			u.stopSearch()
+			u.goSearch(parseLimits(fields[1:]))
+		case "stop":
+			u.stopSearch()
+		case "quit":
+			u.stopSearch()
+			return
+		}
+	}
+	u.stopSearch()
+}
+
+// Handles "setoption name NAME value VALUE"
+func (u *uciSession) setOption(args []string) {
+	if len(args) < 4 || args[0] != "name" || args[2] != "value" {
+		return
+	}
+	switch strings.ToLower(args[1]) {
+	case "hash":
+		if mb, err := strconv.Atoi(args[3]); err == nil && mb > 0 {
+			bot.SetHashSize(mb)
+		}
+	case "threads":
+		if threads, err := strconv.Atoi(args[3]); err == nil && threads > 0 {
+			bot.Threads = threads
+		}
+	case "pvs":
+		bot.Options.PVS = args[3] == "true"
+	case "nullmove":
+		bot.Options.NullMove = args[3] == "true"
+	case "lmr":
+		bot.Options.LMR = args[3] == "true"
+	case "aspiration":
+		bot.Options.Aspiration = args[3] == "true"
+	case "checkextension":
+		bot.Options.CheckExtension = args[3] == "true"
+	case "pawnextension":
+		bot.Options.PawnExtension = args[3] == "true"
+	case "singularextension":
+		bot.Options.SingularExtension = args[3] == "true"
+	case "variety":
+		if points, err := strconv.Atoi(args[3]); err == nil && points >= 0 {
+			bot.Variety = float64(points)
+		}
+	case "deterministic":
+		bot.Deterministic = args[3] == "true"
+	case "multipv":
+		if lines, err := strconv.Atoi(args[3]); err == nil && lines > 0 {
+			bot.MultiPV = lines
+		}
+	}
+}
+
+// Handles "position startpos|fen ... moves ..."
+func (u *uciSession) position(args []string) {
+	if len(args) == 0 {
+		return
+	}
+
+	moves := []string{}
+	fen := START_FEN
+	switch args[0] {
+	case "startpos":
+		args = args[1:]
+	case "fen":
+		args = args[1:]
+		fenFields := []string{}
+		for len(args) > 0 && args[0] != "moves" {
+			fenFields = append(fenFields, args[0])
+			args = args[1:]
+		}
+		fen = strings.Join(fenFields, " ")
+	default:
+		return
+	}
+	if len(args) > 0 && args[0] == "moves" {
+		moves = args[1:]
+	}
+
+	board := engine.Chessboard{}
+	board.FromFen(fen)
+	for _, move := range moves {
+		if !board.MakeUCIMove(move) {
+			u.send("info string illegal move %s", move)
+			break
+		}
+	}
+	u.board = board
+}
+
+// Parses the arguments of a "go" command
+func parseLimits(args []string) uciLimits {
+	limits := uciLimits{}
+	for i := 0; i < len(args); i++ {
+		if args[i] == "infinite" {
+			limits.infinite = true
+			continue
+		}
+		if i+1 >= len(args) {
+			break
+		}
+		value, err := strconv.Atoi(args[i+1])
+		if err != nil {
+			continue
+		}
+		ms := time.Duration(value) * time.Millisecond
+		switch args[i] {
+		case "depth":
+			limits.depth = value
+		case "movetime":
+			limits.movetime = ms
+		case "wtime":
+			limits.wtime = ms
+		case "btime":
+			limits.btime = ms
+		case "winc":
+			limits.winc = ms
+		case "binc":
+			limits.binc = ms
+		case "movestogo":
+			limits.movestogo = value
+		default:
+			continue
+		}
+		i++
+	}
+	return limits
+}
+
+// Limits of the search for TEAM
+func (l uciLimits) search(team bool) engine.SearchLimits {
+	limits := engine.SearchLimits{}
+	if l.movetime > 0 {
+		limits = engine.MoveTime(l.movetime)
+	} else if team {
+		limits = engine.Clock{Remaining: l.wtime, Increment: l.winc, MovesToGo: l.movestogo}.Limits()
+	} else {
+		limits = engine.Clock{Remaining: l.btime, Increment: l.binc, MovesToGo: l.movestogo}.Limits()
+	}
+
+	limits.Depth = l.depth
+	if limits.Depth <= 0 && l.infinite {
+		limits.Depth = engine.MAX_SEARCH_DEPTH
+	}
+	return limits
+}
+
+// Formats the score of a root LINE as a UCI score for TEAM, the side to move
+func uciScore(line engine.RootLine, team bool) string {
+	if moves, mate := mateIn(line.Mate, team); mate {
+		return fmt.Sprintf("mate %d", moves)
+	}
+	score := line.Score
+	if !team {
+		score = -score
+	}
+	return fmt.Sprintf("cp %d", int(score))
+}
+
+// Starts searching the current position in the background
+func (u *uciSession) goSearch(limits uciLimits) {
+	board := u.board.Duplicate()
+	team := board.ToMove()
+
+	search := limits.search(team)
+
+	release := make(chan struct{})
+	if limits.infinite {
+		u.release = release
+	}
+
+	bot.ClearStop()
+	u.searching.Add(1)
+	go func() {
+		defer u.searching.Done()
+
+		info := bot.Think(&board, search, func(info engine.SearchInfo) {
+			nps := int(float64(info.States) / math.Max(info.Elapsed.Seconds(), 0.001))
+			for i, line := range info.Lines {
+				u.send("info depth %d multipv %d score %s nodes %d nps %d time %d pv %s",
+					info.Depth, i+1, uciScore(line, team), info.States, nps, info.Elapsed.Milliseconds(), strings.Join(board.LineUCI(line.PV), " "))
+			}
+			u.send("info string cutoffs %d first move %.1f%%", info.Stats.Cutoffs, info.Stats.FirstMoveRate())
+			u.send("info string aspiration searches %d fail low %d fail high %d", info.Aspiration.Searches, info.Aspiration.FailLows, info.Aspiration.FailHighs)
+		})
+
+		// An infinite search only reports its move after "stop"
+		if limits.infinite {
+			<-release
+		}
+
+		best := "0000"
+		if !info.Move.Invalid() {
+			best = board.MoveUCI(info.Move)
+		}
+		u.send("bestmove %s", best)
+	}()
+}
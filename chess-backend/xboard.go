@@ -0,0 +1,308 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"yrk06/chess-backend/engine"
+)
+
+var xboardMode = flag.Bool("xboard", false, "speak the CECP (xboard/winboard) protocol over stdin/stdout instead of starting the server")
+
+// CECP session over a reader and a writer
+type xboardSession struct {
+	out   io.Writer
+	outMu sync.Mutex
+
+	// Game played so far, replayed on undo
+	startFen string
+	history  []string
+	board    engine.Chessboard
+
+	// Engine state
+	force      bool
+	engineSide bool
+	post       bool
+
+	// Time control
+	mps        int
+	base       time.Duration
+	inc        time.Duration
+	st         time.Duration
+	sd         int
+	engineTime time.Duration
+
+	searching sync.WaitGroup
+}
+
+// Writes a line to the GUI
+func (x *xboardSession) send(format string, a ...interface{}) {
+	x.outMu.Lock()
+	defer x.outMu.Unlock()
+	fmt.Fprintf(x.out, format+"\n", a...)
+}
+
+// Interrupts the running search (if any) and waits for it to finish
+func (x *xboardSession) stopSearch() {
+	bot.Stop()
+	x.searching.Wait()
+}
+
+// Runs the CECP loop until "quit" or the end of the input
+func start_xboard(in io.Reader, out io.Writer) {
+	x := &xboardSession{out: out}
+	x.newGame()
+
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		args := fields[1:]
+
+		switch fields[0] {
+		case "xboard", "accepted", "rejected", "random", "hard", "easy", "computer", "otim":
+			// Nothing to do
+		case "protover":
+			x.send("feature myname=\"eXtreme Go Chess\" usermove=1 setboard=1 ping=1 sigint=0 sigterm=0 colors=0 analyze=0 memory=1 smp=1 done=1")
+		case "ping":
+			x.send("pong %s", strings.Join(args, " "))
+		case "new":
+			x.stopSearch()
+			x.newGame()
+		case "force":
+			x.stopSearch()
+			x.force = true
+		case "go":
+			x.stopSearch()
+			x.force = false
+			x.engineSide = x.board.ToMove()
+			x.think()
+		case "?":
+			// Move now
+			bot.Stop()
+		case "usermove":
+			x.stopSearch()
+			if len(args) == 0 {
+				continue
+			}
+			if !x.play(args[0]) {
+				x.send("Illegal move: %s", args[0])
+				continue
+			}
+			if !x.force && x.board.ToMove() == x.engineSide {
+				x.think()
+			}
+		case "setboard":
+			x.stopSearch()
+			x.startFen = strings.Join(args, " ")
+			x.replay(nil)
+		case "level":
+			x.level(args)
+		case "st":
+			if len(args) > 0 {
+				seconds, _ := strconv.Atoi(args[0])
+				x.st = time.Duration(seconds) * time.Second
+			}
+		case "sd":
+			if len(args) > 0 {
+				x.sd, _ = strconv.Atoi(args[0])
+			}
+		case "time":
+			if len(args) > 0 {
+				centiseconds, _ := strconv.Atoi(args[0])
+				x.engineTime = time.Duration(centiseconds) * 10 * time.Millisecond
+			}
+		case "undo":
+			x.stopSearch()
+			x.takeBack(1)
+		case "remove":
+			x.stopSearch()
+			x.takeBack(2)
+		case "result":
+			x.stopSearch()
+			x.force = true
+		case "memory":
+			x.stopSearch()
+			if len(args) > 0 {
+				if mb, err := strconv.Atoi(args[0]); err == nil && mb > 0 {
+					bot.SetHashSize(mb)
+				}
+			}
+		case "cores":
+			x.stopSearch()
+			if len(args) > 0 {
+				if threads, err := strconv.Atoi(args[0]); err == nil && threads > 0 {
+					bot.Threads = threads
+				}
+			}
+		case "post":
+			x.post = true
+		case "nopost":
+			x.post = false
+		case "quit":
+			x.stopSearch()
+			return
+		default:
+			x.send("Error (unknown command): %s", fields[0])
+		}
+	}
+	x.stopSearch()
+}
+
+// Resets the board to the start position with the engine playing black
+func (x *xboardSession) newGame() {
+	bot.NewGame()
+
+	x.force = false
+	x.engineSide = false
+	x.st = 0
+	x.sd = 0
+	x.startFen = START_FEN
+	x.replay(nil)
+}
+
+// Loads the start position and plays MOVES on it
+func (x *xboardSession) replay(moves []string) {
+	x.board = engine.Chessboard{}
+	x.board.FromFen(x.startFen)
+	x.history = []string{}
+	for _, move := range moves {
+		if !x.play(move) {
+			break
+		}
+	}
+}
+
+// Plays MOVE (coordinate notation) on the board and returns TRUE if it was valid
+func (x *xboardSession) play(move string) bool {
+	if !x.board.MakeUCIMove(move) {
+		return false
+	}
+	x.history = append(x.history, move)
+	return true
+}
+
+// Takes back the last N moves
+func (x *xboardSession) takeBack(n int) {
+	if n > len(x.history) {
+		n = len(x.history)
+	}
+	x.replay(x.history[:len(x.history)-n])
+}
+
+// Handles "level MPS BASE INC", BASE is either minutes or minutes:seconds
+func (x *xboardSession) level(args []string) {
+	if len(args) < 3 {
+		return
+	}
+	x.mps, _ = strconv.Atoi(args[0])
+
+	base := strings.SplitN(args[1], ":", 2)
+	minutes, _ := strconv.Atoi(base[0])
+	seconds := 0
+	if len(base) > 1 {
+		seconds, _ = strconv.Atoi(base[1])
+	}
+	x.base = time.Duration(minutes)*time.Minute + time.Duration(seconds)*time.Second
+	x.engineTime = x.base
+
+	inc, _ := strconv.ParseFloat(args[2], 64)
+	x.inc = time.Duration(inc * float64(time.Second))
+}
+
+// Limits of the search for the next move
+func (x *xboardSession) limits() engine.SearchLimits {
+	limits := engine.SearchLimits{}
+	if x.st > 0 {
+		limits = engine.MoveTime(x.st)
+	} else if x.engineTime > 0 {
+		clock := engine.Clock{Remaining: x.engineTime, Increment: x.inc}
+		if x.mps > 0 {
+			clock.MovesToGo = x.mps - (len(x.history)/2)%x.mps
+		}
+		limits = clock.Limits()
+	}
+	limits.Depth = x.sd
+	return limits
+}
+
+// Searches the current position in the background and plays the best move
+func (x *xboardSession) think() {
+	if x.announceResult() {
+		return
+	}
+
+	board := x.board.Duplicate()
+	team := board.ToMove()
+
+	limits := x.limits()
+
+	post := x.post
+
+	bot.ClearStop()
+	x.searching.Add(1)
+	go func() {
+		defer x.searching.Done()
+
+		info := bot.Think(&board, limits, func(info engine.SearchInfo) {
+			if !post {
+				return
+			}
+			// ply score time(centiseconds) nodes pv
+			score := int(info.Score)
+			if moves, mate := mateIn(info.Mate, team); mate {
+				score = 100000 + moves
+				if moves < 0 {
+					score = -100000 + moves
+				}
+			} else if !team {
+				score = -score
+			}
+			x.send("%d %d %d %d %s", info.Depth, score, info.Elapsed.Milliseconds()/10, info.States, strings.Join(board.LineSAN(info.PV), " "))
+		})
+		if info.Move.Invalid() {
+			return
+		}
+
+		best := board.MoveUCI(info.Move)
+		x.play(best)
+		x.send("move %s", best)
+		x.announceResult()
+	}()
+}
+
+// Sends the result if the game is over. Returns TRUE if it was
+func (x *xboardSession) announceResult() bool {
+	team := x.board.ToMove()
+	if len(x.board.PossibleMoves(team)) != 0 {
+		if x.board.FiftyMoveRule() {
+			x.send("1/2-1/2 {50 move rule}")
+			return true
+		}
+		if x.board.InsufficientMaterial() {
+			x.send("1/2-1/2 {Insufficient material}")
+			return true
+		}
+		if x.board.ThreefoldRepetition() {
+			x.send("1/2-1/2 {3-fold repetition}")
+			return true
+		}
+		return false
+	}
+
+	if x.board.VerifyState(team) {
+		x.send("1/2-1/2 {Stalemate}")
+	} else if team {
+		x.send("0-1 {Black mates}")
+	} else {
+		x.send("1-0 {White mates}")
+	}
+	return true
+}
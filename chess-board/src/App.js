@@ -3,20 +3,39 @@ import { useState } from "react";
 import getQueryVariable from "./services/queryParser";
 import wsfunctions from "./services/wsservice";
 import { converters } from 'fen-reader'
-import { Col, Container, Progress, Row, } from 'reactstrap'
+import { Button, ButtonGroup, Col, Container, Progress, Row, } from 'reactstrap'
 
 import logo from './images/XGC.png';
 
+// Pieces a pawn can promote to, sent as the last field of the move
+const promotionPieces = [
+  { key: 'q', name: 'Queen' },
+  { key: 'r', name: 'Rook' },
+  { key: 'b', name: 'Bishop' },
+  { key: 'n', name: 'Knight' },
+]
+
+// Checks if PIECE (wP, bN...) moving to TARGET_SQUARE is a pawn reaching the last rank
+const isPromotion = (piece, targetSquare) => {
+  return piece.charAt(1) === 'P' && targetSquare.charAt(1) === (piece.charAt(0) === 'w' ? '8' : '1')
+}
+
 const App = (props) => {
   const [boardPos, setBoardPos] = useState("rnbqkbnr/pppppppp/11111111/11111111/11111111/11111111/PPPPPPPP/RNBQKBNR w KQkq - 0 1")
 
   const [evalScore, setEval] = useState(50)
 
+  const [promotion, setPromotion] = useState('q')
+
+  const [claimable, setClaimable] = useState(false)
+
+  const [pv, setPV] = useState("")
+
 
   const side = getQueryVariable("s") ? getQueryVariable("s") : "white"
   const ai = getQueryVariable("t") ? getQueryVariable("t") : "echo"
 
-  wsfunctions.connectGame({ setBoardPos, s: side, t: ai, setEval })
+  wsfunctions.connectGame({ setBoardPos, s: side, t: ai, setEval, setClaimable, setPV })
 
 
   return (
@@ -41,6 +60,7 @@ const App = (props) => {
               if (sourceSquare === targetSquare) {
                 return
               }
+              setClaimable(false)
               console.log(`${piece} moving from ${sourceSquare} to ${targetSquare}`)
 
               {
@@ -51,10 +71,10 @@ const App = (props) => {
                   newBoard[key] = board[key].charAt(1) + String(board[key].charAt(0)).toUpperCase();
                 }
                 delete newBoard[sourceSquare]
-                newBoard[targetSquare] = piece
+                newBoard[targetSquare] = isPromotion(piece, targetSquare) ? piece.charAt(0) + promotion.toUpperCase() : piece
                 setBoardPos(newBoard)
               }
-              wsfunctions.sendMove(piece, sourceSquare, targetSquare)
+              wsfunctions.sendMove(piece, sourceSquare, targetSquare, isPromotion(piece, targetSquare) ? promotion : null)
 
 
 
@@ -65,6 +85,26 @@ const App = (props) => {
         </Col>
       </Row>
 
+      <Row className="mx-auto mt-2" style={{ width: "560px" }}>
+        <Col className="text-center">
+          <span style={{ color: 'rgb(66, 245, 242)', marginRight: '10px' }}>Promote to</span>
+          <ButtonGroup>
+            {promotionPieces.map(({ key, name }) => (
+              <Button key={key} active={promotion === key} onClick={() => setPromotion(key)}
+                style={{ backgroundColor: promotion === key ? 'rgb(66, 245, 242)' : 'rgb(71, 71, 71)', color: promotion === key ? 'black' : 'white', border: '1px solid black' }}>
+                {name}
+              </Button>
+            ))}
+          </ButtonGroup>
+          {claimable &&
+            <Button className="ms-3" onClick={() => { setClaimable(false); wsfunctions.claimDraw() }}
+              style={{ backgroundColor: 'rgb(237,65,123)', color: 'black', border: '1px solid black' }}>
+              Claim draw
+            </Button>
+          }
+        </Col>
+      </Row>
+
       <Row className="mx-auto mt-2" style={{ width: "560px" }}>
         <Col >
           <div className="progress" style={{ backgroundColor:'rgb(41,41,41)',height: "60px", width: "560px",padding: '5px' }}>
@@ -80,6 +120,15 @@ const App = (props) => {
         </Col>
       </Row>
 
+      {pv &&
+        <Row className="mx-auto mt-2" style={{ width: "560px" }}>
+          <Col className="text-center">
+            <span style={{ color: 'rgb(66, 245, 242)', marginRight: '10px' }}>Expected line</span>
+            <span style={{ color: 'white' }}>{pv}</span>
+          </Col>
+        </Row>
+      }
+
     </Container>
   )
 }
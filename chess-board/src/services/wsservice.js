@@ -19,10 +19,13 @@ const connectGame = (state) => {
         wsclose = true
     }
     ws.onmessage = (data) => {
-        const {setBoardPos, setEval} = state
+        const {setBoardPos, setEval, setClaimable, setPV} = state
         console.log(data.data)
 
-        if (data.data.startsWith("eval")) {
+        if (data.data === "repetition") {
+            // Threefold repetition, the player may claim a draw
+            setClaimable(true)
+        } else if (data.data.startsWith("eval")) {
             const limite = 2000
             const value = Math.max(Math.min(parseFloat(data.data.split(" ")[1]), limite),-limite)
 
@@ -31,6 +34,15 @@ const connectGame = (state) => {
             console.log( ( (value/limite)/2.0 + 0.5 ) * 100.0 );
             setEval( ( (value/limite)/2.0 + 0.5 ) * 100.0 )
 
+        } else if (data.data.startsWith("pv ")) {
+            // Line the bot expects, in SAN, starting with its last move
+            setPV(data.data.slice(3))
+
+        } else if (data.data.startsWith("mate")) {
+            // Mate found by the bot, in moves: positive when white mates
+            const moves = parseInt(data.data.split(" ")[1])
+            setEval(moves > 0 ? 100.0 : 0.0)
+
         } else {
             const board = converters.fen2json(data.data.split(" ")[0])
             //console.log(board)
@@ -38,7 +50,7 @@ const connectGame = (state) => {
             for(let key of Object.keys(board)){
                 newBoard[key] = board[key].charAt(1) + String(board[key].charAt(0)).toUpperCase();
             }
-            if (data.data === "Checkmate") {
+            if (data.data === "Checkmate" || data.data === "insufficient material" || data.data === "Draw") {
                 gameover = true
             }
             setBoardPos(data.data)
@@ -56,10 +68,20 @@ const restartGame = (state) => {
     connectGame(state)
 }
 
-const sendMove = (piece, startingSquare, targetSquare) => {
-    ws.send(`${piece}-${startingSquare}-${targetSquare}`)
+// PROMOTION (q, r, b or n) is only sent when a pawn reaches the last rank
+const sendMove = (piece, startingSquare, targetSquare, promotion) => {
+    if (promotion) {
+        ws.send(`${piece}-${startingSquare}-${targetSquare}-${promotion}`)
+    } else {
+        ws.send(`${piece}-${startingSquare}-${targetSquare}`)
+    }
+}
+
+// Claims a draw by threefold repetition
+const claimDraw = () => {
+    ws.send("claim")
 }
 
-const wsfunctions = {connectGame, sendMove, restartGame}
+const wsfunctions = {connectGame, sendMove, restartGame, claimDraw}
 
 export default wsfunctions;